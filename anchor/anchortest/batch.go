@@ -0,0 +1,41 @@
+package anchortest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor/internal/batchserver"
+)
+
+// Performs the next status transition of the batch if it is due, returning whether it was
+// performed. The core must be locked.
+func (s *Server) advance(b *batchserver.Batch) bool {
+	o := s.opts
+	switch b.Status {
+	case anchor.Batch_BATCHING:
+		if at := b.CreatedAt.Add(o.BatchInterval); !s.now.Before(at) {
+			s.core.Transition(b, anchor.Batch_QUEUING, at)
+			return true
+		}
+	case anchor.Batch_QUEUING:
+		if at := b.FlushedAt.Add(o.StepInterval); !s.now.Before(at) {
+			s.core.Transition(b, anchor.Batch_PROCESSING, at)
+			return true
+		}
+	case anchor.Batch_PROCESSING:
+		if at := b.StartedAt.Add(o.StepInterval); !s.now.Before(at) {
+			// Submits the batch root to the anchor.
+			b.BuildTree()
+			txn := sha256.Sum256([]byte(b.Id + b.Root))
+			s.core.Submit(b, hex.EncodeToString(txn[:]), at)
+			return true
+		}
+	case anchor.Batch_PENDING:
+		if at := b.SubmittedAt.Add(o.StepInterval); !s.now.Before(at) {
+			s.core.Transition(b, anchor.Batch_CONFIRMED, at)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+// Package anchortest provides an in-process anchor service for testing code using the anchor
+// client without a running anchor service.
+//
+// The server batches submitted hashes and moves each batch through BATCHING, QUEUING,
+// PROCESSING, PENDING and CONFIRMED as its clock is advanced, emitting the transitions to batch
+// subscribers and producing CHP_PATH proofs which verify against the anchored batch roots.
+package anchortest
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor/internal/batchserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Options represents the server options.
+type Options struct {
+	// The time a batch accepts hashes before it is flushed.
+	BatchInterval time.Duration
+	// The number of hashes which flushes a batch immediately. Zero disables the limit.
+	BatchSize int
+	// The time a flushed batch spends in each following status.
+	StepInterval time.Duration
+	// The initial time of the server's clock.
+	StartTime time.Time
+	// The anchors served. All are running initially.
+	Anchors []anchor.Anchor_Type
+}
+
+// Option func.
+type Option func(*Options)
+
+// WithBatchInterval sets the time a batch accepts hashes before it is flushed.
+func WithBatchInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.BatchInterval = d
+	}
+}
+
+// WithBatchSize sets the number of hashes which flushes a batch immediately.
+func WithBatchSize(size int) Option {
+	return func(o *Options) {
+		o.BatchSize = size
+	}
+}
+
+// WithStepInterval sets the time a flushed batch spends in each following status.
+func WithStepInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.StepInterval = d
+	}
+}
+
+// WithStartTime sets the initial time of the server's clock.
+func WithStartTime(t time.Time) Option {
+	return func(o *Options) {
+		o.StartTime = t
+	}
+}
+
+// WithAnchors sets the anchors served.
+func WithAnchors(anchors ...anchor.Anchor_Type) Option {
+	return func(o *Options) {
+		o.Anchors = anchors
+	}
+}
+
+// Server is a fake anchor service served in-process over a buffered connection. Time only passes
+// when the clock is advanced with Advance.
+type Server struct {
+	opts     *Options
+	core     *batchserver.Server
+	listener *bufconn.Listener
+	server   *grpc.Server
+	now      time.Time // guarded by the core's lock
+	nextId   int
+}
+
+// NewServer creates and starts a new server.
+func NewServer(opts ...Option) *Server {
+	o := &Options{
+		BatchInterval: time.Minute,
+		StepInterval:  10 * time.Second,
+		StartTime:     time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		Anchors:       []anchor.Anchor_Type{anchor.Anchor_ETH, anchor.Anchor_HEDERA_MAINNET},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	s := &Server{
+		opts:     o,
+		listener: bufconn.Listen(1024 * 1024),
+		server:   grpc.NewServer(),
+		now:      o.StartTime,
+	}
+	s.core = batchserver.New(&batchserver.Options{
+		Name:       "anchortest",
+		Anchors:    o.Anchors,
+		BatchSize:  o.BatchSize,
+		Now:        func() time.Time { return s.now },
+		NewBatchId: s.newBatchId,
+		Flush: func(b *batchserver.Batch) {
+			s.core.Transition(b, anchor.Batch_QUEUING, s.now)
+		},
+	})
+	anchor.RegisterAnchorServiceServer(s.server, s.core)
+	go s.server.Serve(s.listener)
+	return s
+}
+
+// Close stops the server, ending all open streams.
+func (s *Server) Close() {
+	s.server.Stop()
+	s.core.Close()
+}
+
+// Dialer returns the dialer connecting to the server, for use with grpc.WithContextDialer.
+func (s *Server) Dialer() func(context.Context, string) (net.Conn, error) {
+	return func(context.Context, string) (net.Conn, error) {
+		return s.listener.Dial()
+	}
+}
+
+// Client connects a new anchor client to the server.
+func (s *Server) Client(opts ...anchor.ClientOption) (*anchor.Client, error) {
+	return anchor.Connect(append([]anchor.ClientOption{
+		anchor.WithAddress("bufconn"),
+		anchor.WithInsecure(true),
+		anchor.WithCredentials(""),
+		anchor.WithDialOptions(grpc.WithContextDialer(s.Dialer())),
+	}, opts...)...)
+}
+
+// Now returns the current time of the server's clock.
+func (s *Server) Now() time.Time {
+	s.core.Lock()
+	defer s.core.Unlock()
+	return s.now
+}
+
+// Advance moves the server's clock forward, performing every batch status transition due in
+// that time.
+func (s *Server) Advance(d time.Duration) {
+	s.core.Lock()
+	defer s.core.Unlock()
+	s.now = s.now.Add(d)
+	for _, b := range s.core.Batches() {
+		for s.advance(b) {
+		}
+	}
+}
+
+// FailBatch moves the batch to ERROR with the given message.
+func (s *Server) FailBatch(batchId string, message string) error {
+	return s.core.FailBatch(batchId, message)
+}
+
+// StopAnchor stops the anchor, rejecting submissions to it until it is started.
+func (s *Server) StopAnchor(anchorType anchor.Anchor_Type) {
+	s.core.SetAnchorStatus(anchorType, anchor.Anchor_STOPPED)
+}
+
+// StartAnchor starts the stopped anchor.
+func (s *Server) StartAnchor(anchorType anchor.Anchor_Type) {
+	s.core.SetAnchorStatus(anchorType, anchor.Anchor_RUNNING)
+}
+
+// Batch returns the batch with the given ID, or nil if not found.
+func (s *Server) Batch(batchId string) *anchor.Batch {
+	return s.core.Batch(batchId)
+}
+
+// AwaitSubscribers blocks until at least n batch subscriptions are open, so the clock can be
+// advanced without a subscriber missing any transition.
+func (s *Server) AwaitSubscribers(ctx context.Context, n int) error {
+	return s.core.AwaitSubscribers(ctx, n)
+}
+
+// Returns the ID of a new batch, derived from a counter so IDs are deterministic.
+func (s *Server) newBatchId() string {
+	s.nextId++
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("batch-%d", s.nextId))))[:32]
+}
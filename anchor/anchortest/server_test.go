@@ -0,0 +1,155 @@
+package anchortest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+)
+
+// Returns n distinct hashes.
+func testHashes(n int) []string {
+	hashes := make([]string, n)
+	for i := range hashes {
+		hashes[i] = fmt.Sprintf("%064x", i+1)
+	}
+	return hashes
+}
+
+func newTestClient(t *testing.T, opts ...Option) (*Server, *anchor.Client) {
+	server := NewServer(opts...)
+	t.Cleanup(server.Close)
+	client, err := server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestServer_Transitions(t *testing.T) {
+	server, client := newTestClient(t, WithBatchInterval(time.Minute), WithStepInterval(time.Second))
+	ctx := context.Background()
+	p, err := client.SubmitProof(ctx, testHashes(1)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []struct {
+		advance time.Duration
+		status  anchor.Batch_Status
+	}{
+		{59 * time.Second, anchor.Batch_BATCHING},
+		{time.Second, anchor.Batch_QUEUING},
+		{time.Second, anchor.Batch_PROCESSING},
+		{time.Second, anchor.Batch_PENDING},
+		{time.Second, anchor.Batch_CONFIRMED},
+	}
+	for _, e := range exp {
+		server.Advance(e.advance)
+		b, err := client.GetBatch(ctx, p.BatchId, anchor.Anchor_ETH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.GetStatus() != e.status {
+			t.Fatalf("expected %s, got %s", e.status, b.GetStatus())
+		}
+	}
+	b := server.Batch(p.BatchId)
+	if !b.GetFinalizedAt().AsTime().Equal(server.Now()) {
+		t.Fatal("expected the batch to be finalized at the current time")
+	}
+}
+
+func TestServer_Subscribe(t *testing.T) {
+	server, client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sub, err := client.SubscribeBatches(ctx, anchor.Anchor_ETH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if err := server.AwaitSubscribers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SubmitProof(ctx, testHashes(1)[0]); err != nil {
+		t.Fatal(err)
+	}
+	server.Advance(time.Hour)
+	exp := []anchor.Batch_Status{anchor.Batch_BATCHING, anchor.Batch_QUEUING, anchor.Batch_PROCESSING, anchor.Batch_PENDING, anchor.Batch_CONFIRMED}
+	for _, status := range exp {
+		e := <-sub.Events()
+		if e.Err != nil || e.Status != status {
+			t.Fatalf("expected %s, got %s (%v)", status, e.Status, e.Err)
+		}
+	}
+}
+
+func TestServer_Proofs(t *testing.T) {
+	server, client := newTestClient(t, WithBatchSize(5))
+	ctx := context.Background()
+	hashes := testHashes(7)
+	proofs := make([]*anchor.AnchorProof, len(hashes))
+	for i, h := range hashes {
+		p, err := client.SubmitProof(ctx, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proofs[i] = p
+	}
+	if proofs[0].BatchId != proofs[4].BatchId || proofs[4].BatchId == proofs[5].BatchId {
+		t.Fatal("expected the batch to be flushed after 5 hashes")
+	}
+	server.Advance(time.Hour)
+	roots := make(map[string]string)
+	for _, h := range proofs {
+		p, err := client.GetProof(ctx, h.Id, anchor.Anchor_ETH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := anchor.VerifyOffline(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Root != server.Batch(p.BatchId).GetHash() {
+			t.Fatalf("proof of '%s' does not match its batch root", p.Hash)
+		}
+		roots[p.BatchId] = res.Root
+		verified, err := client.VerifyProof(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !verified.Verified || verified.ProvenHash != p.Hash {
+			t.Fatalf("proof of '%s' not verified: %s", p.Hash, verified.Error)
+		}
+	}
+	if len(roots) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(roots))
+	}
+}
+
+func TestServer_VerifyProof_Unconfirmed(t *testing.T) {
+	server, client := newTestClient(t, WithStepInterval(time.Minute))
+	ctx := context.Background()
+	p, err := client.SubmitProof(ctx, testHashes(1)[0], anchor.SubmitProofWithSkipBatching(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Submitted to the anchor, but not yet confirmed.
+	server.Advance(2 * time.Minute)
+	if p, err = client.GetProof(ctx, p.Id, anchor.Anchor_ETH); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != anchor.Batch_PENDING {
+		t.Fatalf("expected PENDING, got %s", p.Status)
+	}
+	res, err := client.VerifyProof(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Verified {
+		t.Fatal("unconfirmed proof should not be verified")
+	}
+}
@@ -0,0 +1,48 @@
+package anchor
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// backoff computes jittered exponential delays between retry attempts.
+type backoff struct {
+	min      time.Duration // the initial delay
+	max      time.Duration // the maximum delay
+	attempts int           // the attempts made since the last reset
+}
+
+// next returns the delay before the next attempt and records the attempt. The delay doubles on
+// each attempt and is randomized between half and the full value.
+func (b *backoff) next() time.Duration {
+	d := b.min
+	for i := 0; i < b.attempts && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		d = b.max
+	}
+	b.attempts++
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// reset resets the attempts after a successful call.
+func (b *backoff) reset() {
+	b.attempts = 0
+}
+
+// sleep waits for the duration, returning early with the context's error if it is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package anchor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SubmitProofResult is the result of submitting a single hash with SubmitProofs.
+type SubmitProofResult struct {
+	Hash  string       // the submitted hash
+	Proof *AnchorProof // the proof, nil if the submission failed
+	Err   error        // the error of the submission
+}
+
+// SubmitProofsOptions options.
+type SubmitProofsOptions struct {
+	// The maximum number of proofs submitted at once.
+	Concurrency int
+	// The options used to submit each proof.
+	SubmitOptions []SubmitProofOption
+}
+
+// SubmitProofsOption func.
+type SubmitProofsOption func(*SubmitProofsOptions)
+
+// SubmitProofsWithConcurrency sets the maximum number of proofs submitted at once.
+func SubmitProofsWithConcurrency(n int) SubmitProofsOption {
+	return func(o *SubmitProofsOptions) {
+		o.Concurrency = n
+	}
+}
+
+// SubmitProofsWithSubmitOptions sets the options used to submit each proof, e.g. the anchor type
+// or SubmitProofWithAwaitConfirmed.
+func SubmitProofsWithSubmitOptions(opts ...SubmitProofOption) SubmitProofsOption {
+	return func(o *SubmitProofsOptions) {
+		o.SubmitOptions = opts
+	}
+}
+
+// SubmitProofs submits every hash with at most Concurrency submissions at once, returning the
+// results in the order of the hashes. When awaiting confirmation, a single subscription is shared
+// by all the proofs of each batch. The error is non-nil if any submission failed, in which case
+// it wraps the first failure.
+func (c *Client) SubmitProofs(ctx context.Context, hashes []string, opts ...SubmitProofsOption) ([]*SubmitProofResult, error) {
+	bo := &SubmitProofsOptions{
+		Concurrency: 8,
+	}
+	for _, opt := range opts {
+		opt(bo)
+	}
+	o := newSubmitProofOptions(bo.SubmitOptions...)
+	results := make([]*SubmitProofResult, len(hashes))
+	for i, h := range hashes {
+		results[i] = &SubmitProofResult{Hash: h}
+	}
+	// Submit without awaiting, confirmation is awaited per batch below.
+	submitOpts := append(append([]SubmitProofOption{}, bo.SubmitOptions...), SubmitProofWithAwaitConfirmed(false))
+	c.forEach(results, bo.Concurrency, func(r *SubmitProofResult) {
+		r.Proof, r.Err = c.SubmitProof(ctx, r.Hash, submitOpts...)
+	})
+
+	if o.AwaitConfirmed {
+		if o.AwaitTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, o.AwaitTimeout)
+			defer cancel()
+		}
+		batches := make(map[string][]*SubmitProofResult)
+		order := make([]string, 0)
+		for _, r := range results {
+			if r.Err != nil || r.Proof.Status == Batch_CONFIRMED {
+				continue
+			}
+			if _, ok := batches[r.Proof.BatchId]; !ok {
+				order = append(order, r.Proof.BatchId)
+			}
+			batches[r.Proof.BatchId] = append(batches[r.Proof.BatchId], r)
+		}
+		var wg sync.WaitGroup
+		for _, id := range order {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				c.awaitBatch(ctx, id, o.AnchorType, batches[id], bo.Concurrency)
+			}(id)
+		}
+		wg.Wait()
+	}
+
+	failed := 0
+	var first error
+	for _, r := range results {
+		if r.Err != nil {
+			if first == nil {
+				first = r.Err
+			}
+			failed++
+		}
+	}
+	if first != nil {
+		return results, fmt.Errorf("%d of %d proofs failed: %w", failed, len(results), first)
+	}
+	return results, nil
+}
+
+// Waits for the batch to be confirmed, then fetches the confirmed proof of every result. If the
+// batch fails or the subscription ends, the error is set on every result instead.
+func (c *Client) awaitBatch(ctx context.Context, batchId string, anchorType Anchor_Type, results []*SubmitProofResult, concurrency int) {
+	fail := func(err error) {
+		for _, r := range results {
+			r.Proof, r.Err = nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sub, err := c.SubscribeBatches(ctx, anchorType, SubscribeWithBatchId(batchId))
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer sub.Close()
+	// The batch may have finished before the subscription started.
+	status := Batch_Status(-1)
+	var batch *Batch
+	if b, err := c.GetBatch(ctx, batchId, anchorType); err == nil {
+		status, batch = b.GetStatus(), b
+	}
+	for !batchDone(status) {
+		e, ok := <-sub.Events()
+		if !ok {
+			fail(ErrSubscriptionEnded)
+			return
+		}
+		if e.Err != nil {
+			if e.Err == io.EOF {
+				e.Err = ErrSubscriptionEnded
+			}
+			fail(e.Err)
+			return
+		}
+		status, batch = e.Status, e.Batch
+	}
+	if status == Batch_ERROR {
+		fail(&BatchError{Batch: batch})
+		return
+	}
+	c.forEach(results, concurrency, func(r *SubmitProofResult) {
+		p, err := c.GetProof(ctx, r.Proof.Id, anchorType)
+		if err != nil {
+			r.Proof, r.Err = nil, err
+			return
+		}
+		r.Proof = p
+	})
+}
+
+// Calls fn for every result with at most n calls at once.
+func (c *Client) forEach(results []*SubmitProofResult, n int, fn func(r *SubmitProofResult)) {
+	if n <= 0 {
+		n = 1
+	}
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for _, r := range results {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *SubmitProofResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(r)
+		}(r)
+	}
+	wg.Wait()
+}
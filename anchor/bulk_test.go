@@ -0,0 +1,72 @@
+package anchor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// countingAnchorClient tracks the maximum number of concurrent submissions.
+type countingAnchorClient struct {
+	fakeAnchorClient
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (c *countingAnchorClient) SubmitProof(ctx context.Context, in *SubmitProofRequest, opts ...grpc.CallOption) (*Proof, error) {
+	c.mu.Lock()
+	c.current++
+	if c.current > c.max {
+		c.max = c.current
+	}
+	c.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	c.mu.Lock()
+	c.current--
+	c.mu.Unlock()
+	return &Proof{Hash: in.GetHash(), BatchId: "1", BatchStatus: Batch_BATCHING}, nil
+}
+
+func TestClient_SubmitProofs_Concurrency(t *testing.T) {
+	fake := &countingAnchorClient{}
+	client := &Client{anchor: fake}
+	hashes := make([]string, 20)
+	for i := range hashes {
+		hashes[i] = "ab"
+	}
+	results, err := client.SubmitProofs(context.Background(), hashes, SubmitProofsWithConcurrency(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 20 {
+		t.Fatalf("expected 20 results, got %d", len(results))
+	}
+	if fake.max > 4 || fake.max < 2 {
+		t.Fatalf("expected at most 4 concurrent submissions, got %d", fake.max)
+	}
+}
+
+func TestClient_SubmitProofs_SharedSubscription(t *testing.T) {
+	fake := &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_CONFIRMED}}},
+		},
+		batch: &Batch{Id: "1", Status: Batch_PENDING},
+		proof: &Proof{Hash: "ab", BatchId: "1", BatchStatus: Batch_CONFIRMED},
+	}
+	client := &Client{anchor: &countingAnchorClient{fakeAnchorClient: *fake}}
+	results, err := client.SubmitProofs(context.Background(), []string{"ab", "ab", "ab"},
+		SubmitProofsWithSubmitOptions(SubmitProofWithAwaitConfirmed(true), SubmitProofWithAwaitTimeout(5*time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Proof.Status != Batch_CONFIRMED {
+			t.Fatal("expected every proof to be confirmed")
+		}
+	}
+}
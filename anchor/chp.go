@@ -0,0 +1,184 @@
+package anchor
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// VerifiedAnchor represents an anchor reached while evaluating a proof, along with the value
+// that is expected to be found on the anchor (e.g. the ETH transaction data or Hedera message).
+type VerifiedAnchor struct {
+	Label    string   // the label of the branch containing the anchor
+	Type     string   // the anchor type
+	AnchorId string   // the anchor ID, e.g. the transaction ID
+	Uris     []string // the anchor's verification URIs
+	Expected string   // hex encoded value expected to be found on the anchor
+}
+
+// OfflineVerification is the result of evaluating a proof without contacting the anchor service.
+type OfflineVerification struct {
+	Hash    string            // the hash the proof starts from
+	Root    string            // the computed value anchored by the proof
+	Anchors []*VerifiedAnchor // the anchors reached by the proof
+}
+
+// VerifyOffline evaluates the given proof without contacting the anchor service, ensuring the
+// proof data starts from the proof's hash.
+func VerifyOffline(proof *AnchorProof) (*OfflineVerification, error) {
+	var res *OfflineVerification
+	var err error
+	switch proof.Format {
+	case Proof_CHP_PATH, Proof_CHP_PATH_SIGNED:
+		res, err = VerifyCHPPath(proof.Data)
+	case Proof_ETH_TRIE, Proof_ETH_TRIE_SIGNED:
+		res, err = VerifyETHTrie(proof.Data, proof.Hash)
+	default:
+		return nil, fmt.Errorf("%w '%s'", ErrUnsupportedFormat, proof.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if proof.Hash != "" && res.Hash != proof.Hash {
+		return nil, fmt.Errorf("proof data hash '%s' does not match proof hash '%s'", res.Hash, proof.Hash)
+	}
+	return res, nil
+}
+
+// VerifyCHPPath walks the decoded CHP_PATH proof data, applying every operation of every branch
+// starting from the proof hash, and returns the value expected on each anchor found.
+func VerifyCHPPath(data map[string]interface{}) (*OfflineVerification, error) {
+	path, err := ToCHPPath(data)
+	if err != nil {
+		return nil, err
+	}
+	return path.Verify()
+}
+
+// Verify applies every operation of every branch starting from the hash, and returns the value
+// expected on each anchor found.
+func (p *CHPPath) Verify() (*OfflineVerification, error) {
+	if p.Hash == "" {
+		return nil, errors.New("proof data is missing 'hash'")
+	}
+	value, err := hex.DecodeString(p.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("proof hash '%s' is not hex", p.Hash)
+	}
+	res := &OfflineVerification{
+		Hash:    p.Hash,
+		Anchors: make([]*VerifiedAnchor, 0),
+	}
+	if err := evaluateCHPBranches(value, p.Branches, res); err != nil {
+		return nil, err
+	}
+	if len(res.Anchors) == 0 {
+		return nil, errors.New("proof does not contain any anchors")
+	}
+	res.Root = res.Anchors[0].Expected
+	return res, nil
+}
+
+// Evaluates each branch starting from the given value. Nested branches continue from the value
+// computed by their parent branch.
+func evaluateCHPBranches(value []byte, branches []*CHPBranch, res *OfflineVerification) error {
+	for _, branch := range branches {
+		if branch == nil {
+			return errors.New("proof branch must be an object")
+		}
+		current := append([]byte{}, value...)
+		for _, op := range branch.Ops {
+			next, err := evaluateCHPOp(current, op, branch.Label, res)
+			if err != nil {
+				return err
+			}
+			current = next
+		}
+		if err := evaluateCHPBranches(current, branch.Branches, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applies a single op to the value and returns the result.
+func evaluateCHPOp(value []byte, op *CHPOp, label string, res *OfflineVerification) ([]byte, error) {
+	switch {
+	case op == nil:
+		return nil, fmt.Errorf("invalid op in branch '%s'", label)
+	case op.L != "":
+		return append(chpValue(op.L), value...), nil
+	case op.R != "":
+		return append(value, chpValue(op.R)...), nil
+	case op.Op != "":
+		return chpHash(op.Op, value)
+	case op.Anchors != nil:
+		for _, a := range op.Anchors {
+			if a == nil {
+				return nil, fmt.Errorf("invalid anchor in branch '%s'", label)
+			}
+			anchor := &VerifiedAnchor{
+				Label:    label,
+				Type:     a.Type,
+				AnchorId: a.AnchorId,
+				Expected: hex.EncodeToString(value),
+				Uris:     append(make([]string, 0), a.Uris...),
+			}
+			res.Anchors = append(res.Anchors, anchor)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unknown op in branch '%s'", label)
+}
+
+// Converts an l/r op value to bytes. Hex values are decoded, anything else is treated as
+// a UTF-8 string.
+func chpValue(v string) []byte {
+	if len(v) > 0 && len(v)%2 == 0 {
+		if b, err := hex.DecodeString(v); err == nil {
+			return b
+		}
+	}
+	return []byte(v)
+}
+
+// Hashes the value using the given CHP op.
+func chpHash(op string, value []byte) ([]byte, error) {
+	var h crypto.Hash
+	rounds := 1
+	switch op {
+	case "sha-224":
+		h = crypto.SHA224
+	case "sha-256":
+		h = crypto.SHA256
+	case "sha-256-x2":
+		h = crypto.SHA256
+		rounds = 2
+	case "sha-384":
+		h = crypto.SHA384
+	case "sha-512":
+		h = crypto.SHA512
+	case "sha3-224":
+		h = crypto.SHA3_224
+	case "sha3-256":
+		h = crypto.SHA3_256
+	case "sha3-384":
+		h = crypto.SHA3_384
+	case "sha3-512":
+		h = crypto.SHA3_512
+	default:
+		return nil, fmt.Errorf("unknown op '%s'", op)
+	}
+	if !h.Available() {
+		return nil, fmt.Errorf("hash function for op '%s' is not available", op)
+	}
+	for i := 0; i < rounds; i++ {
+		hasher := h.New()
+		hasher.Write(value)
+		value = hasher.Sum(nil)
+	}
+	return value, nil
+}
@@ -0,0 +1,124 @@
+package anchor
+
+import (
+	"testing"
+)
+
+const (
+	chpPathData       = "eJykkbGS0zAQQH+G1rG0K9lWqszwC1Q0nt3VGmsIlsfWBa4EGtp8w4XhYCiBkv/I3zC5wHU00D7pvZ3Z/XC/kzwVfVN+jqXM67auX2OKm7y8qGWkNM05TaU+4Knczvrl6SM6jbSO5x3IAC4y+YENoVFotEMGEs9BIahl21j1YgdqHXht0TjEBgJ2HDpwXy+ZPsV+ylHPTyIZQPFSSRd8Za2GihCGCghIIbKIuh8PynrDr1IpejV7Kt/AgK0MVuCfAW6d3Vp8/piXvPxj/mL+LX/PC00y6np893FPrPvvc+Rey9hfcF766/tdntfj209Xth7fP+zyTmj/+fe3FM+7VnCAltk2rlWN1nhpPEb0A6m6BkNk10Egb7yG0EqnTL7V0HaN+GCa082S1uP55Z8zXtObqIfNvOQ8EO91k3J90CUNt7WWsf7fkb8CAAD//5Arw98="
+	chpPathSignedData = "eJykk81u42QUhsW9sItS299f7EojNQlJbadN0tSZZiKk6Ps5jp36b/w5cexdYcO24gpgimZALIEl91GuBnUCs2PDbN+j5zmL95zvPlzIPKvgWP0ZVVWhzw2jxrE6y8utISMeZ0UeZ5VxwE9VU8Avw0/RU8R19HyBZIiIEpyGwuTYBMTAxgJxSYUDyAFLWMwCKq2Q9wii0MMmwZghB9vCsRH59UWzidUmyxU8f6m4ibCksitth3YtC5wuxyjsIo44ICWkBPLHR0TvRRpXFZzIDa9+QyayuibuIhogfE6tc+ysP+llXv5P/Qv5X/oPouSZjEA/fvNTwgUkvxdKbKCKNi9xXm5O83d5ob9/+KH864uvdbw9X/kjHGz7xjoOgjv3zTbo93eLIYui3d2g09BVGvaYTGa7vY/T7cHvpO5xWI5XSZiWC1/O7th2r+KJumuKW/92dDRZHXlXU1Td3tdXFVt49k2THjqGo/3w8v4rgia05lmg+6OlnBNbFfF+/bbvCjdctrvFZVJgx7ePK6VeW7Ry0d5r0CCrr83aa66u7NYdIrM93t7PsY0c10+HMhgVLVgMrrPgcsCaTm6k48FgeOP0SxqtlokdGrCnenFIp3FPYqjzdBr0k2vYLjrRdHIcJ7YPi3w+iiZtvhedYzvy9PoNuG7ribH3VuPVzJwJFs7DpdDptJnK4evlbDdOm3o6h/lCUS+ptD+HzNuhm0vTayfr/qtXDz/mxXsd8S6i7OH9qQH9+O3Hy30nefLzP6XE6vkiBIlB2hAiEFgqIQEJAjaTXHLGCev1ECGOgzChWDk9RnrEIcABGMHCtKynfRnrx+f7f5/mpD5TcDgryjwPuUjgLM6NA5Rx2BhQRcbnrvw7AAD//ywtQbc="
+
+	chpPathHash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+)
+
+func TestVerifyCHPPath(t *testing.T) {
+	data, err := DecodeProof(chpPathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifyCHPPath(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hash != chpPathHash || res.Root != chpPathHash {
+		t.Fatalf("unexpected root '%s'", res.Root)
+	}
+	if len(res.Anchors) != 1 {
+		t.Fatalf("expected 1 anchor, got %d", len(res.Anchors))
+	}
+	a := res.Anchors[0]
+	if a.Label != "pdb_eth_anchor_branch" || a.Type != "cal" || a.AnchorId != "7c3f27bb1647eed105c653d35faee4639db4829a505e997c8eba57e9786c5906" {
+		t.Fatal("unexpected anchor")
+	}
+	if len(a.Uris) != 1 {
+		t.Fatal("expected anchor uri")
+	}
+}
+
+func TestVerifyCHPPath_Signed(t *testing.T) {
+	data, err := DecodeProof(chpPathSignedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifyCHPPath(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Root != "57c2da4d152aad1645b206b2a66b814c83a05c689c27710556a73c47e7b3fbaf" {
+		t.Fatalf("unexpected root '%s'", res.Root)
+	}
+}
+
+func TestVerifyCHPPath_NestedBranches(t *testing.T) {
+	// The shape produced by merkle.Tree.AddPathToProof. 'a' is hashed with 'b' to produce 'ab'.
+	a := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	b := "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+	ab := "e5a01fee14e0ed5c48714f22180f25ad8365b53f9779f79dc4a3d7e93963f94a"
+	ops := []interface{}{
+		map[string]string{"r": b},
+		map[string]string{"op": "sha-256"},
+	}
+	data := map[string]interface{}{
+		"hash": a,
+		"branches": []map[string]interface{}{
+			{
+				"label": "merkle",
+				"ops":   &ops,
+				"branches": []interface{}{
+					map[string]interface{}{
+						"label": "pdb_eth_anchor_branch",
+						"ops": []interface{}{
+							map[string]interface{}{
+								"anchors": []interface{}{
+									map[string]interface{}{"type": "cal", "anchor_id": "1"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	res, err := VerifyCHPPath(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hash != a || res.Root != ab {
+		t.Fatalf("unexpected root '%s'", res.Root)
+	}
+	if res.Anchors[0].Label != "pdb_eth_anchor_branch" {
+		t.Fatal("unexpected anchor label")
+	}
+}
+
+func TestVerifyCHPPath_UnknownOp(t *testing.T) {
+	data := map[string]interface{}{
+		"hash": chpPathHash,
+		"branches": []interface{}{
+			map[string]interface{}{
+				"label": "test",
+				"ops": []interface{}{
+					map[string]interface{}{"op": "md5"},
+				},
+			},
+		},
+	}
+	if _, err := VerifyCHPPath(data); err == nil {
+		t.Fatal("expected unknown op error")
+	}
+}
+
+func TestVerifyOffline_HashMismatch(t *testing.T) {
+	data, err := DecodeProof(chpPathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &AnchorProof{
+		Format: Proof_CHP_PATH,
+		Hash:   "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb",
+		Data:   data,
+	}
+	if _, err := VerifyOffline(p); err == nil {
+		t.Fatal("expected hash mismatch error")
+	}
+}
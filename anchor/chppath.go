@@ -0,0 +1,195 @@
+package anchor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CHPPath is the decoded data of a CHP_PATH or CHP_PATH_SIGNED proof, following the Chainpoint v3
+// format. The path starts from Hash, and each branch applies its ops to the value computed by its
+// parent before continuing into its own branches.
+//
+// Keys of the proof which are not modelled by the path's types are kept in their Extra fields, so
+// converting proof data to a path and back does not drop them.
+type CHPPath struct {
+	Context             string       `json:"@context,omitempty"`
+	Type                string       `json:"type,omitempty"`
+	Hash                string       `json:"hash"`
+	HashIdNode          string       `json:"hash_id_node,omitempty"`
+	HashSubmittedNodeAt string       `json:"hash_submitted_node_at,omitempty"`
+	HashIdCore          string       `json:"hash_id_core,omitempty"`
+	HashSubmittedCoreAt string       `json:"hash_submitted_core_at,omitempty"`
+	Branches            []*CHPBranch `json:"branches,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // the keys not modelled by the path
+}
+
+// CHPBranch is a labelled list of ops, followed by the branches continuing from its result.
+type CHPBranch struct {
+	Label    string       `json:"label,omitempty"`
+	Ops      []*CHPOp     `json:"ops"`
+	Branches []*CHPBranch `json:"branches,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // the keys not modelled by the branch
+}
+
+// CHPOp is a single op of a branch. Exactly one of its fields is set: L or R concatenates the
+// value on the left or right, Op hashes the value, and Anchors lists the anchors of the value.
+type CHPOp struct {
+	L       string       `json:"l,omitempty"`
+	R       string       `json:"r,omitempty"`
+	Op      string       `json:"op,omitempty"`
+	Anchors []*CHPAnchor `json:"anchors,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // the keys not modelled by the op
+}
+
+// CHPAnchor is an anchor holding the value computed by the ops preceding it.
+type CHPAnchor struct {
+	Type     string   `json:"type"`
+	AnchorId string   `json:"anchor_id"`
+	Uris     []string `json:"uris,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // the keys not modelled by the anchor
+}
+
+// The types of the path without their JSON methods.
+type (
+	chpPathJSON   CHPPath
+	chpBranchJSON CHPBranch
+	chpOpJSON     CHPOp
+	chpAnchorJSON CHPAnchor
+)
+
+// MarshalJSON marshals the path, including its extra keys.
+func (p CHPPath) MarshalJSON() ([]byte, error) {
+	return marshalExtra((*chpPathJSON)(&p), p.Extra)
+}
+
+// UnmarshalJSON unmarshals the path, keeping the keys it does not model in Extra.
+func (p *CHPPath) UnmarshalJSON(b []byte) error {
+	return unmarshalExtra(b, (*chpPathJSON)(p), &p.Extra)
+}
+
+// MarshalJSON marshals the branch, including its extra keys.
+func (b CHPBranch) MarshalJSON() ([]byte, error) {
+	return marshalExtra((*chpBranchJSON)(&b), b.Extra)
+}
+
+// UnmarshalJSON unmarshals the branch, keeping the keys it does not model in Extra.
+func (b *CHPBranch) UnmarshalJSON(data []byte) error {
+	return unmarshalExtra(data, (*chpBranchJSON)(b), &b.Extra)
+}
+
+// MarshalJSON marshals the op, including its extra keys.
+func (o CHPOp) MarshalJSON() ([]byte, error) {
+	return marshalExtra((*chpOpJSON)(&o), o.Extra)
+}
+
+// UnmarshalJSON unmarshals the op, keeping the keys it does not model in Extra.
+func (o *CHPOp) UnmarshalJSON(b []byte) error {
+	return unmarshalExtra(b, (*chpOpJSON)(o), &o.Extra)
+}
+
+// MarshalJSON marshals the anchor, including its extra keys.
+func (a CHPAnchor) MarshalJSON() ([]byte, error) {
+	return marshalExtra((*chpAnchorJSON)(&a), a.Extra)
+}
+
+// UnmarshalJSON unmarshals the anchor, keeping the keys it does not model in Extra.
+func (a *CHPAnchor) UnmarshalJSON(b []byte) error {
+	return unmarshalExtra(b, (*chpAnchorJSON)(a), &a.Extra)
+}
+
+// Marshals the struct followed by the extra keys it does not already hold.
+func marshalExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+	m := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for k, e := range extra {
+		if _, ok := m[k]; !ok {
+			m[k] = e
+		}
+	}
+	return json.Marshal(m)
+}
+
+// Unmarshals the object into the struct, setting extra to the keys not modelled by the struct.
+func unmarshalExtra(b []byte, v interface{}, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		delete(m, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+	}
+	if len(m) == 0 {
+		m = nil
+	}
+	*extra = m
+	return nil
+}
+
+// DecodeCHPPath decodes the CHP path from its wire format (base64 -> zlib -> msgpack).
+func DecodeCHPPath(data string) (*CHPPath, error) {
+	m, err := DecodeProof(data)
+	if err != nil {
+		return nil, err
+	}
+	return ToCHPPath(m)
+}
+
+// ToCHPPath converts decoded proof data, e.g. AnchorProof.Data, to the CHP path. Keys not modelled
+// by the path are kept in the Extra fields.
+func ToCHPPath(data map[string]interface{}) (*CHPPath, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	p := &CHPPath{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("invalid CHP path: %s", err.Error())
+	}
+	return p, nil
+}
+
+// Encode encodes the CHP path into its wire format (msgpack -> zlib -> base64).
+func (p *CHPPath) Encode() (string, error) {
+	m, err := p.Map()
+	if err != nil {
+		return "", err
+	}
+	return EncodeProof(m)
+}
+
+// Map converts the CHP path to decoded proof data, the form held by AnchorProof.Data.
+func (p *CHPPath) Map() (map[string]interface{}, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CHPPath returns the proof data as a CHP path. The proof must be of a CHP_PATH format.
+func (a *AnchorProof) CHPPath() (*CHPPath, error) {
+	if a.Format != Proof_CHP_PATH && a.Format != Proof_CHP_PATH_SIGNED {
+		return nil, fmt.Errorf("%w '%s': proof is not a CHP path", ErrUnsupportedFormat, a.Format)
+	}
+	return ToCHPPath(a.Data)
+}
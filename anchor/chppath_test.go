@@ -0,0 +1,145 @@
+package anchor
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCHPPath(t *testing.T) {
+	for _, data := range []string{chpPathData, chpPathSignedData} {
+		exp, err := DecodeProof(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := DecodeCHPPath(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Hash != exp["hash"] || len(p.Branches) == 0 {
+			t.Fatalf("unexpected path %+v", p)
+		}
+		// Every field of the path is modelled, so the round trip is lossless.
+		m, err := p.Map()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var norm map[string]interface{}
+		j, _ := json.Marshal(exp)
+		if err := json.Unmarshal(j, &norm); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, norm) {
+			t.Fatalf("expected %v, got %v", norm, m)
+		}
+		encoded, err := p.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeCHPPath(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p, decoded) {
+			t.Fatal("decoded path does not match the encoded path")
+		}
+	}
+}
+
+func TestToCHPPath_Extra(t *testing.T) {
+	// Keys the path does not model are kept at every level.
+	data := map[string]interface{}{
+		"hash":  "ab",
+		"extra": "path",
+		"branches": []interface{}{map[string]interface{}{
+			"label": "pdb_eth_anchor_branch",
+			"extra": []interface{}{"branch"},
+			"ops": []interface{}{
+				map[string]interface{}{"r": "cd", "extra": "op"},
+				map[string]interface{}{"anchors": []interface{}{
+					map[string]interface{}{"type": "cal", "anchor_id": "1", "extra": map[string]interface{}{"n": 1.0}},
+				}},
+			},
+		}},
+	}
+	p, err := ToCHPPath(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.Extra["extra"]) != `"path"` || string(p.Branches[0].Ops[0].Extra["extra"]) != `"op"` {
+		t.Fatalf("expected the extra keys to be kept, got %v", p.Extra)
+	}
+	if p.Branches[0].Ops[1].Extra != nil {
+		t.Fatal("expected no extra keys")
+	}
+	m, err := p.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, data) {
+		t.Fatalf("expected %v, got %v", data, m)
+	}
+	// Paths held by value are marshalled the same.
+	a, _ := json.Marshal(p)
+	b, _ := json.Marshal(*p)
+	if string(a) != string(b) {
+		t.Fatalf("expected %s, got %s", a, b)
+	}
+}
+
+func TestCHPPath_Verify(t *testing.T) {
+	// 'a' is hashed with 'b' to produce 'ab', which is anchored.
+	a := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	b := "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+	ab := "e5a01fee14e0ed5c48714f22180f25ad8365b53f9779f79dc4a3d7e93963f94a"
+	p := &CHPPath{
+		Hash: a,
+		Branches: []*CHPBranch{{
+			Label: "pdb_batch_branch",
+			Ops:   []*CHPOp{{R: b}, {Op: "sha-256"}},
+			Branches: []*CHPBranch{{
+				Label: "pdb_eth_anchor_branch",
+				Ops:   []*CHPOp{{Anchors: []*CHPAnchor{{Type: "cal", AnchorId: "1", Uris: []string{"uri"}}}}},
+			}},
+		}},
+	}
+	res, err := p.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Root != ab || res.Anchors[0].Label != "pdb_eth_anchor_branch" || res.Anchors[0].AnchorId != "1" {
+		t.Fatalf("unexpected verification %+v", res)
+	}
+	encoded, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := DecodeProof(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := VerifyCHPPath(data); err != nil || res.Root != ab {
+		t.Fatalf("expected the encoded path to verify, got %v", err)
+	}
+}
+
+func TestAnchorProof_CHPPath(t *testing.T) {
+	data, err := DecodeProof(chpPathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := (&AnchorProof{Format: Proof_CHP_PATH, Data: data}).CHPPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Hash != chpPathHash {
+		t.Fatalf("unexpected hash '%s'", p.Hash)
+	}
+	if _, err := (&AnchorProof{Format: Proof_ETH_TRIE, Data: data}).CHPPath(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected unsupported format, got %v", err)
+	}
+	if _, err := ToCHPPath(map[string]interface{}{"branches": "invalid"}); err == nil {
+		t.Fatal("expected invalid branches error")
+	}
+}
@@ -2,11 +2,13 @@ package anchor
 
 import (
 	context "context"
+	"crypto"
 	"crypto/tls"
-	"errors"
+	"crypto/x509"
+	"encoding/hex"
 	"io"
 	"os"
-	"strings"
+	"time"
 
 	grpc "google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -28,6 +30,23 @@ type ClientOptions struct {
 	Credentials string
 	// Establishes an insecure connection.
 	Insecure bool
+	// The root CAs used to verify the server certificate. Nil uses the system roots.
+	RootCAs *x509.CertPool
+	// The PEM encoded root CA file used to verify the server certificate, added to RootCAs.
+	RootCAFile string
+	// Overrides the server name used to verify the server certificate.
+	ServerName string
+	// The client certificates presented for mutual TLS.
+	Certificates []tls.Certificate
+	// The PEM encoded client certificate and key files presented for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// Skips verification of the server certificate. Only use for testing.
+	InsecureSkipVerify bool
+	// The policy for retrying failed unary calls. Nil disables retries.
+	RetryPolicy *RetryPolicy
+	// Additional grpc dial options, e.g. a custom dialer.
+	DialOptions []grpc.DialOption
 }
 
 // ClientOption func.
@@ -51,6 +70,67 @@ func WithInsecure(insecure bool) ClientOption {
 	}
 }
 
+// WithRootCAs verifies the server certificate against the given root CAs instead of the
+// system roots.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(o *ClientOptions) {
+		o.RootCAs = pool
+	}
+}
+
+// WithRootCAFile verifies the server certificate against the root CAs in the PEM encoded file
+// instead of the system roots.
+func WithRootCAFile(file string) ClientOption {
+	return func(o *ClientOptions) {
+		o.RootCAFile = file
+	}
+}
+
+// WithServerName overrides the server name used to verify the server certificate.
+func WithServerName(name string) ClientOption {
+	return func(o *ClientOptions) {
+		o.ServerName = name
+	}
+}
+
+// WithClientCertificate presents the certificate for mutual TLS.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(o *ClientOptions) {
+		o.Certificates = append(o.Certificates, cert)
+	}
+}
+
+// WithClientCertificateFile presents the certificate in the PEM encoded certificate and key
+// files for mutual TLS.
+func WithClientCertificateFile(certFile string, keyFile string) ClientOption {
+	return func(o *ClientOptions) {
+		o.CertFile = certFile
+		o.KeyFile = keyFile
+	}
+}
+
+// WithInsecureSkipVerify skips verification of the server certificate, leaving the connection
+// open to man-in-the-middle attacks. Only use for testing.
+func WithInsecureSkipVerify(skip bool) ClientOption {
+	return func(o *ClientOptions) {
+		o.InsecureSkipVerify = skip
+	}
+}
+
+// WithRetryPolicy retries failed unary calls according to the policy.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(o *ClientOptions) {
+		o.RetryPolicy = policy.withDefaults()
+	}
+}
+
+// WithDialOptions adds grpc dial options to the connection.
+func WithDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(o *ClientOptions) {
+		o.DialOptions = append(o.DialOptions, opts...)
+	}
+}
+
 // Connect creates a new anchor client and performs all the grpc connections.
 func Connect(opts ...ClientOption) (*Client, error) {
 	const (
@@ -76,11 +156,16 @@ func Connect(opts ...ClientOption) (*Client, error) {
 	if o.Insecure {
 		dialOpts = append(dialOpts, grpc.WithInsecure())
 	} else {
-		config := &tls.Config{
-			InsecureSkipVerify: true,
+		config, err := o.tlsConfig()
+		if err != nil {
+			return nil, err
 		}
 		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(config)))
 	}
+	if o.RetryPolicy != nil {
+		dialOpts = append(dialOpts, grpc.WithUnaryInterceptor(o.RetryPolicy.unaryInterceptor()))
+	}
+	dialOpts = append(dialOpts, o.DialOptions...)
 	conn, err := grpc.Dial(o.Address, dialOpts...)
 	if err != nil {
 		return nil, err
@@ -101,7 +186,7 @@ func (c *Client) Close() error {
 func (c *Client) GetAnchors(ctx context.Context) ([]*Anchor, error) {
 	res, err := c.anchor.GetAnchors(ctx, &emptypb.Empty{})
 	if err != nil {
-		return nil, err
+		return nil, toError(err)
 	}
 	anchors := make([]*Anchor, 0)
 	for {
@@ -110,7 +195,7 @@ func (c *Client) GetAnchors(ctx context.Context) ([]*Anchor, error) {
 			return anchors, nil
 		}
 		if err != nil {
-			return nil, err
+			return nil, toError(err)
 		}
 		anchors = append(anchors, a)
 	}
@@ -118,34 +203,56 @@ func (c *Client) GetAnchors(ctx context.Context) ([]*Anchor, error) {
 
 // GetAnchor will retreive information about a single anchor.
 func (c *Client) GetAnchor(ctx context.Context, anchorType Anchor_Type) (*Anchor, error) {
-	return c.anchor.GetAnchor(ctx, &AnchorRequest{
+	a, err := c.anchor.GetAnchor(ctx, &AnchorRequest{
 		Type: anchorType,
 	})
+	return a, toError(err)
 }
 
 // GetBatch retrieves a single batch information.
 func (c *Client) GetBatch(ctx context.Context, batchId string, anchorType Anchor_Type) (*Batch, error) {
-	return c.anchor.GetBatch(ctx, &BatchRequest{
+	b, err := c.anchor.GetBatch(ctx, &BatchRequest{
 		BatchId:    batchId,
 		AnchorType: anchorType,
 	})
+	return b, toError(err)
 }
 
-// GetProof retrieves a proof matching the given hash and batch ID.
+// GetProof retrieves a proof matching the given hash and batch ID. The anchor type may be nil if
+// the ID includes it.
 func (c *Client) GetProof(ctx context.Context, id string, anchorType interface{}) (*AnchorProof, error) {
-	s := strings.Split(id, ":")
-	at, err := getAnchorType(anchorType)
+	p, err := ParseProofID(id)
+	if err != nil {
+		return nil, err
+	}
+	at, err := p.anchorType(anchorType)
 	if err != nil {
 		return nil, err
 	}
+	return c.getProof(ctx, p, at)
+}
+
+// GetProofByID retrieves the proof with the given ID, which must include the anchor type.
+func (c *Client) GetProofByID(ctx context.Context, id ProofID) (*AnchorProof, error) {
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	at, err := id.anchorType(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.getProof(ctx, id, at)
+}
+
+func (c *Client) getProof(ctx context.Context, id ProofID, anchorType Anchor_Type) (*AnchorProof, error) {
 	res, err := c.anchor.GetProof(ctx, &ProofRequest{
-		Hash:       s[0],
-		BatchId:    s[1],
-		AnchorType: at,
+		Hash:       id.Hash,
+		BatchId:    id.BatchId,
+		AnchorType: anchorType,
 		WithBatch:  true,
 	})
 	if err != nil {
-		return nil, err
+		return nil, toError(err)
 	}
 	p := &AnchorProof{}
 	if e := p.FromProof(res); e != nil {
@@ -154,6 +261,37 @@ func (c *Client) GetProof(ctx context.Context, id string, anchorType interface{}
 	return p, nil
 }
 
+// VerifyProofResult represents the outcome of a proof verification.
+type VerifyProofResult struct {
+	// Whether the proof was verified by the anchor service.
+	Verified bool
+	// The hash proven by the proof.
+	ProvenHash string
+	// The error reported by the anchor service, if any.
+	Error string
+}
+
+// VerifyProof verifies the given proof with the anchor service.
+func (c *Client) VerifyProof(ctx context.Context, proof *AnchorProof) (*VerifyProofResult, error) {
+	data, err := proof.EncodeData()
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.anchor.VerifyProof(ctx, &VerifyProofRequest{
+		AnchorType: proof.AnchorType,
+		Format:     proof.Format,
+		Data:       data,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &VerifyProofResult{
+		Verified:   res.GetVerified(),
+		ProvenHash: res.GetProvenHash(),
+		Error:      res.GetError(),
+	}, nil
+}
+
 // SubmitProofOptions options.
 type SubmitProofOptions struct {
 	// The anchor type.
@@ -164,6 +302,12 @@ type SubmitProofOptions struct {
 	Format Proof_Format
 
 	AwaitConfirmed bool
+	// The maximum time to wait for the proof to be confirmed. Zero waits until the
+	// context is done.
+	AwaitTimeout time.Duration
+	// The algorithm the hash was computed with, checked against the hash length. Zero only
+	// checks the hash is hex.
+	Algorithm crypto.Hash
 }
 
 type SubmitProofOption func(o *SubmitProofOptions)
@@ -192,9 +336,24 @@ func SubmitProofWithAwaitConfirmed(awaitConfirmed bool) SubmitProofOption {
 	}
 }
 
-// SubmitProof submits a new proof to the anchor service.
-func (c *Client) SubmitProof(ctx context.Context, hash string, opts ...SubmitProofOption) (p *AnchorProof, e error) {
-	// Set default options
+// SubmitProofWithAlgorithm checks the hash is a digest of the algorithm before submitting it,
+// e.g. merkle.SHA256.Hash().
+func SubmitProofWithAlgorithm(algorithm crypto.Hash) SubmitProofOption {
+	return func(o *SubmitProofOptions) {
+		o.Algorithm = algorithm
+	}
+}
+
+// SubmitProofWithAwaitTimeout sets the maximum time to wait for confirmation when
+// awaiting the proof to be confirmed.
+func SubmitProofWithAwaitTimeout(timeout time.Duration) SubmitProofOption {
+	return func(o *SubmitProofOptions) {
+		o.AwaitTimeout = timeout
+	}
+}
+
+// Returns the submit proof options with the defaults applied.
+func newSubmitProofOptions(opts ...SubmitProofOption) *SubmitProofOptions {
 	o := &SubmitProofOptions{
 		AnchorType:     Anchor_ETH,
 		SkipBatching:   false,
@@ -204,6 +363,17 @@ func (c *Client) SubmitProof(ctx context.Context, hash string, opts ...SubmitPro
 	for _, opt := range opts {
 		opt(o)
 	}
+	return o
+}
+
+// SubmitProof submits a new proof to the anchor service. The hash must be lowercase hex, of the
+// algorithm's digest length if an algorithm is given, otherwise ErrInvalidHash is returned without
+// contacting the anchor service.
+func (c *Client) SubmitProof(ctx context.Context, hash string, opts ...SubmitProofOption) (*AnchorProof, error) {
+	o := newSubmitProofOptions(opts...)
+	if err := validateHash(hash, o.Algorithm); err != nil {
+		return nil, err
+	}
 	// Submit the request
 	req := &SubmitProofRequest{
 		Hash:         hash,
@@ -213,77 +383,78 @@ func (c *Client) SubmitProof(ctx context.Context, hash string, opts ...SubmitPro
 	}
 	res, err := c.anchor.SubmitProof(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, toError(err)
 	}
-	p = &AnchorProof{}
+	p := &AnchorProof{}
 	if e := p.FromProof(res); e != nil {
 		return nil, e
 	}
-	// If await confirmed, subscribe
-	done := false
 	if o.AwaitConfirmed {
-		c.SubscribeProof(ctx, p.Id, p.AnchorType, func(proof *AnchorProof, err error) {
-			if err != nil {
-				e = err
-				done = true
-				return
-			} else {
-				if proof.Status == Batch_CONFIRMED.String() {
-					p = proof
-					done = true
-					return
-				}
-			}
-		})
-		for !done {
+		return c.awaitConfirmed(ctx, p, o.AwaitTimeout)
+	}
+	return p, nil
+}
+
+// SubmitDigest submits the raw digest to the anchor service, hex encoding it as the proof's hash.
+func (c *Client) SubmitDigest(ctx context.Context, digest []byte, opts ...SubmitProofOption) (*AnchorProof, error) {
+	return c.SubmitProof(ctx, hex.EncodeToString(digest), opts...)
+}
+
+// awaitConfirmed blocks until the proof's batch is confirmed, returning the confirmed proof. It
+// returns an error if the batch errors, the subscription ends before confirmation, or the context
+// is done.
+func (c *Client) awaitConfirmed(ctx context.Context, proof *AnchorProof, timeout time.Duration) (*AnchorProof, error) {
+	if proof.Status == Batch_CONFIRMED {
+		return proof, nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	sub, err := c.WatchProofByID(ctx, proof.ProofID())
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Close()
+	for u := range sub.Updates() {
+		if u.Err != nil {
+			return nil, u.Err
+		}
+		if u.Status == Batch_CONFIRMED {
+			return u.Proof, nil
 		}
 	}
-	return
+	return nil, ErrSubscriptionEnded
 }
 
 // Subsribe proof will listen for changes to the proof and return either the updated proof, or an error.
 // Function will complete once proof status returned is either CONFIRMED or ERROR, or context expired.
-func (c *Client) SubscribeProof(ctx context.Context, id string, anchorType interface{}, callback func(proof *AnchorProof, err error)) {
-	s := strings.Split(id, ":")
-	at, err := getAnchorType(anchorType)
-	if err != nil {
-		callback(nil, err)
-		return
-	}
-	res, err := c.anchor.SubscribeBatches(ctx, &SubscribeBatchesRequest{
-		Filter: &BatchRequest{
-			BatchId:    s[1],
-			AnchorType: at,
-		},
-	})
-	if err != nil {
-		callback(nil, err)
-	}
-	go func() {
-		for {
-			b, err := res.Recv()
-			if err == io.EOF {
-				return
-			}
-			if err != nil {
-				callback(nil, err)
-				return
-			}
-			if b.Status == Batch_ERROR {
-				callback(nil, errors.New(b.Error))
-				return
-			} else {
-				// Get the updated proof
-				proof, err := c.GetProof(ctx, id, anchorType)
-				if err != nil {
-					callback(nil, err)
-					return
+// The callback is called from a separate goroutine and receives exactly one final call.
+func (c *Client) SubscribeProof(ctx context.Context, id string, anchorType interface{}, callback func(proof *AnchorProof, err error), opts ...SubscribeOption) {
+	c.subscribeProof(callback)(c.WatchProof(ctx, id, anchorType, opts...))
+}
+
+// SubscribeProofByID is SubscribeProof for a proof ID including the anchor type.
+func (c *Client) SubscribeProofByID(ctx context.Context, id ProofID, callback func(proof *AnchorProof, err error), opts ...SubscribeOption) {
+	c.subscribeProof(callback)(c.WatchProofByID(ctx, id, opts...))
+}
+
+// Returns the func delivering the updates of the subscription to the callback.
+func (c *Client) subscribeProof(callback func(proof *AnchorProof, err error)) func(*ProofSubscription, error) {
+	return func(sub *ProofSubscription, err error) {
+		if err != nil {
+			go callback(nil, err)
+			return
+		}
+		go func() {
+			for u := range sub.Updates() {
+				if u.Err != nil {
+					callback(nil, u.Err)
+				} else {
+					callback(u.Proof, nil)
 				}
-				callback(proof, nil)
 			}
-			if b.Status == Batch_CONFIRMED {
-				return
-			}
-		}
-	}()
+		}()
+	}
 }
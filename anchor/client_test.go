@@ -1,27 +1,59 @@
-package anchor
+package anchor_test
 
 import (
 	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor/anchortest"
 )
 
-func TestConnect(t *testing.T) {
-	client, err := Connect(WithInsecure(true), WithAddress("localhost:10008"))
+const testHash = "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f"
+
+// Starts a fake anchor service and connects a client to it.
+func newTestClient(t *testing.T, opts ...anchortest.Option) (*anchortest.Server, *anchor.Client) {
+	server := anchortest.NewServer(opts...)
+	t.Cleanup(server.Close)
+	client, err := server.Client()
 	if err != nil {
-		t.Fail()
+		t.Fatal(err)
 	}
-	defer client.Close()
+	t.Cleanup(func() { client.Close() })
+	return server, client
 }
 
-func TestClient_GetAnchors(t *testing.T) {
-	client, err := Connect(WithInsecure(true), WithAddress("localhost:10008"))
+// Confirms every batch once n subscriptions are open.
+func confirmWhenSubscribed(t *testing.T, server *anchortest.Server, n int) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.AwaitSubscribers(ctx, n); err != nil {
+			t.Error(err)
+			return
+		}
+		server.Advance(time.Hour)
+	}()
+}
+
+func TestConnect(t *testing.T) {
+	client, err := anchor.Connect(anchor.WithInsecure(true), anchor.WithAddress("localhost:10008"))
 	if err != nil {
-		t.Fail()
+		t.Fatal(err)
 	}
 	defer client.Close()
+}
+
+func TestClient_GetAnchors(t *testing.T) {
+	_, client := newTestClient(t)
 	anchors, err := client.GetAnchors(context.Background())
 	if err != nil {
-		t.FailNow()
+		t.Fatal(err)
 	}
 	// There should always be a length above 0.
 	if !(len(anchors) > 0) {
@@ -30,48 +62,228 @@ func TestClient_GetAnchors(t *testing.T) {
 }
 
 func TestClient_SubmitProof(t *testing.T) {
-	client, err := Connect(WithInsecure(true), WithAddress("localhost:10008"))
-	if err != nil {
-		t.Fail()
-	}
-	defer client.Close()
-	p, err := client.SubmitProof(context.Background(), "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f")
+	server, client := newTestClient(t)
+	p, err := client.SubmitProof(context.Background(), testHash)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if p.Format != Proof_CHP_PATH.String() {
+	if p.Format != anchor.Proof_CHP_PATH {
 		t.Fatal("wrong default format")
 	}
-	if p.AnchorType != Anchor_ETH.String() {
+	if p.AnchorType != anchor.Anchor_ETH {
 		t.Fatal("wrong default anchor type")
 	}
+	sub, err := client.WatchProof(context.Background(), p.Id, p.AnchorType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	confirmWhenSubscribed(t, server, 1)
 	confirmed := false
-	callback := func(p *AnchorProof, err error) {
+	for u := range sub.Updates() {
+		if u.Err != nil {
+			t.Fatal(u.Err)
+		}
+		if u.Proof.Status == anchor.Batch_CONFIRMED {
+			confirmed = true
+		}
+	}
+	if !confirmed {
+		t.Fatal("proof should have been confirmed")
+	}
+}
+
+func TestClient_SubscribeProof(t *testing.T) {
+	server, client := newTestClient(t)
+	p, err := client.SubmitProof(context.Background(), testHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	client.SubscribeProof(context.Background(), p.Id, p.AnchorType, func(p *anchor.AnchorProof, err error) {
 		if err != nil {
-			t.Fatal(err)
+			done <- err
 			return
 		}
-		if p.Status == Batch_CONFIRMED.String() {
-			confirmed = true
+		if p.Status == anchor.Batch_CONFIRMED {
+			done <- nil
+		}
+	})
+	confirmWhenSubscribed(t, server, 1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_SubscribeProof_InvalidID(t *testing.T) {
+	_, client := newTestClient(t)
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	client.SubscribeProof(context.Background(), "invalid", anchor.Anchor_ETH, func(p *anchor.AnchorProof, err error) {
+		// Blocks forever if the callback is called from the subscribing goroutine.
+		<-release
+		done <- err
+	})
+	close(release)
+	if err := <-done; !errors.Is(err, anchor.ErrInvalidProofID) {
+		t.Fatalf("expected invalid proof ID error, got %v", err)
+	}
+}
+
+func TestClient_SubscribeProofByID(t *testing.T) {
+	server, client := newTestClient(t)
+	p, err := client.SubmitProof(context.Background(), testHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := anchor.ParseProofID(p.ProofID().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	client.SubscribeProofByID(context.Background(), id, func(p *anchor.AnchorProof, err error) {
+		if err != nil {
+			done <- err
 			return
 		}
+		if p.Status == anchor.Batch_CONFIRMED {
+			done <- nil
+		}
+	})
+	confirmWhenSubscribed(t, server, 1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
 	}
-	client.SubscribeProof(context.Background(), p.Id, p.AnchorType, callback)
-	for !confirmed {
+	confirmed, err := client.GetProofByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed.Status != anchor.Batch_CONFIRMED {
+		t.Fatalf("expected confirmed proof, got %s", confirmed.Status)
+	}
+}
+
+func TestClient_WatchProof_InvalidID(t *testing.T) {
+	_, client := newTestClient(t)
+	if _, err := client.WatchProof(context.Background(), "invalid", anchor.Anchor_ETH); !errors.Is(err, anchor.ErrInvalidProofID) {
+		t.Fatalf("expected invalid proof ID error, got %v", err)
 	}
 }
 
 func TestClient_SubmitProofWithAwaitConfirmed(t *testing.T) {
-	client, err := Connect(WithInsecure(true), WithAddress("localhost:10008"))
+	server, client := newTestClient(t)
+	confirmWhenSubscribed(t, server, 1)
+	p, err := client.SubmitProof(context.Background(), testHash, anchor.SubmitProofWithAwaitConfirmed(true))
 	if err != nil {
-		t.Fail()
+		t.Fatal(err)
 	}
-	defer client.Close()
-	p, err := client.SubmitProof(context.Background(), "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f", SubmitProofWithAwaitConfirmed(true))
+	if p.Status != anchor.Batch_CONFIRMED {
+		t.Fatal("proof should have been confirmed")
+	}
+}
+
+func TestClient_SubmitProofWithAwaitConfirmed_BatchFailed(t *testing.T) {
+	server, client := newTestClient(t)
+	p, err := client.SubmitProof(context.Background(), testHash)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if p.Status != Batch_CONFIRMED.String() {
-		t.Fatal("proof should have been confirmed")
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.AwaitSubscribers(ctx, 1); err != nil {
+			t.Error(err)
+			return
+		}
+		server.FailBatch(p.BatchId, "insufficient funds")
+	}()
+	// Resubmitting the hash returns the proof in the same batch.
+	_, err = client.SubmitProof(context.Background(), testHash, anchor.SubmitProofWithAwaitConfirmed(true))
+	if !errors.Is(err, anchor.ErrBatchFailed) {
+		t.Fatalf("expected batch failed, got %v", err)
+	}
+}
+
+func TestClient_SubmitProof_AnchorStopped(t *testing.T) {
+	server, client := newTestClient(t)
+	server.StopAnchor(anchor.Anchor_ETH)
+	if _, err := client.SubmitProof(context.Background(), testHash); !errors.Is(err, anchor.ErrAnchorStopped) {
+		t.Fatalf("expected anchor stopped, got %v", err)
+	}
+}
+
+func TestClient_VerifyProof(t *testing.T) {
+	server, client := newTestClient(t)
+	confirmWhenSubscribed(t, server, 1)
+	p, err := client.SubmitProof(context.Background(), testHash, anchor.SubmitProofWithAwaitConfirmed(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.VerifyProof(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Verified {
+		t.Fatalf("proof should have been verified: %s", res.Error)
+	}
+	if res.ProvenHash != p.Hash {
+		t.Fatal("proven hash does not match the proof hash")
+	}
+}
+
+func TestClient_SubmitProofWithAwaitTimeout(t *testing.T) {
+	_, client := newTestClient(t)
+	_, err := client.SubmitProof(context.Background(), testHash,
+		anchor.SubmitProofWithAwaitConfirmed(true), anchor.SubmitProofWithAwaitTimeout(time.Nanosecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestClient_SubmitProofs(t *testing.T) {
+	server, client := newTestClient(t, anchortest.WithBatchSize(4))
+	hashes := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		hashes = append(hashes, fmt.Sprintf("%064x", i))
+	}
+	hashes = append(hashes[:5], append([]string{"not hex"}, hashes[5:]...)...)
+	// The 10 valid hashes fill 3 batches.
+	confirmWhenSubscribed(t, server, 3)
+	results, err := client.SubmitProofs(context.Background(), hashes,
+		anchor.SubmitProofsWithConcurrency(3), anchor.SubmitProofsWithSubmitOptions(anchor.SubmitProofWithAwaitConfirmed(true)))
+	if !errors.Is(err, anchor.ErrInvalidHash) {
+		t.Fatalf("expected the invalid hash to fail, got %v", err)
+	}
+	if len(results) != len(hashes) {
+		t.Fatalf("expected %d results, got %d", len(hashes), len(results))
+	}
+	for i, r := range results {
+		if r.Hash != hashes[i] {
+			t.Fatal("expected the results in the order of the hashes")
+		}
+		if r.Hash == "not hex" {
+			if r.Err == nil || r.Proof != nil {
+				t.Fatal("expected the invalid hash to fail")
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if r.Proof.Hash != r.Hash || r.Proof.Status != anchor.Batch_CONFIRMED {
+			t.Fatalf("expected the proof of '%s' to be confirmed", r.Hash)
+		}
+	}
+}
+
+func TestClient_SubmitDigest(t *testing.T) {
+	_, client := newTestClient(t)
+	digest := sha256.Sum256([]byte("hello"))
+	p, err := client.SubmitDigest(context.Background(), digest[:], anchor.SubmitProofWithAlgorithm(crypto.SHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Hash != hex.EncodeToString(digest[:]) {
+		t.Fatalf("expected the hex encoded digest, got '%s'", p.Hash)
 	}
 }
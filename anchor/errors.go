@@ -0,0 +1,118 @@
+package anchor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The errors returned by the client. Errors returned by the anchor service are mapped from their
+// gRPC status codes, so they can be checked with errors.Is while status.Code still reports the
+// original code.
+var (
+	// The proof ID is not in the 'hash:batchId' form.
+	ErrInvalidProofID = errors.New("invalid proof ID")
+	// The anchor type is unknown.
+	ErrInvalidAnchorType = errors.New("invalid anchor type")
+	// The proof format is unknown or not supported by the operation.
+	ErrUnsupportedFormat = errors.New("unsupported proof format")
+	// The batch holding the proof failed. Use errors.As with *BatchError to get the batch.
+	ErrBatchFailed = errors.New("batch failed")
+	// The hash is not lowercase hex of the expected length.
+	ErrInvalidHash = errors.New("invalid hash")
+	// The subscription ended before the proof was confirmed.
+	ErrSubscriptionEnded = errors.New("subscription ended before the proof was confirmed")
+
+	// The credentials are missing or invalid (Unauthenticated).
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// The credentials do not allow the operation (PermissionDenied).
+	ErrPermissionDenied = errors.New("permission denied")
+	// The anchor is not running (FailedPrecondition).
+	ErrAnchorStopped = errors.New("anchor stopped")
+	// The proof, batch or anchor does not exist (NotFound).
+	ErrNotFound = errors.New("not found")
+	// The request was rejected by the anchor service (InvalidArgument).
+	ErrInvalidArgument = errors.New("invalid argument")
+	// The anchor service is rate limiting the client (ResourceExhausted).
+	ErrRateLimited = errors.New("rate limited")
+	// The anchor service cannot be reached (Unavailable).
+	ErrUnavailable = errors.New("anchor service unavailable")
+)
+
+// The errors matching each gRPC status code. Cancelled and expired calls match the context errors.
+var codeErrors = map[codes.Code]error{
+	codes.Canceled:           context.Canceled,
+	codes.DeadlineExceeded:   context.DeadlineExceeded,
+	codes.Unauthenticated:    ErrUnauthenticated,
+	codes.PermissionDenied:   ErrPermissionDenied,
+	codes.FailedPrecondition: ErrAnchorStopped,
+	codes.NotFound:           ErrNotFound,
+	codes.InvalidArgument:    ErrInvalidArgument,
+	codes.ResourceExhausted:  ErrRateLimited,
+	codes.Unavailable:        ErrUnavailable,
+}
+
+// StatusError is an error returned by the anchor service.
+type StatusError struct {
+	status *status.Status
+}
+
+func (e *StatusError) Error() string {
+	return e.status.Err().Error()
+}
+
+// Code returns the gRPC status code.
+func (e *StatusError) Code() codes.Code {
+	return e.status.Code()
+}
+
+// Message returns the error message sent by the anchor service.
+func (e *StatusError) Message() string {
+	return e.status.Message()
+}
+
+// Unwrap returns the error matching the status code, if any.
+func (e *StatusError) Unwrap() error {
+	return codeErrors[e.status.Code()]
+}
+
+// GRPCStatus returns the status so status.Code works on the error.
+func (e *StatusError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// BatchError is the error of a proof whose batch failed. It matches ErrBatchFailed.
+type BatchError struct {
+	Batch *Batch // the failed batch
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch '%s' failed: %s", e.Batch.GetId(), e.Batch.GetError())
+}
+
+// Is returns whether the target is ErrBatchFailed.
+func (e *BatchError) Is(target error) bool {
+	return target == ErrBatchFailed
+}
+
+// Converts an error returned by the gRPC client to a typed error.
+func toError(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return err
+	}
+	if retryErr, ok := err.(*RetryError); ok {
+		return &RetryError{Attempts: retryErr.Attempts, Err: toError(retryErr.Err)}
+	}
+	if s, ok := status.FromError(err); ok {
+		return &StatusError{status: s}
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package anchor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToError(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		err  error
+	}{
+		{codes.Unauthenticated, ErrUnauthenticated},
+		{codes.FailedPrecondition, ErrAnchorStopped},
+		{codes.NotFound, ErrNotFound},
+		{codes.Unavailable, ErrUnavailable},
+		{codes.DeadlineExceeded, context.DeadlineExceeded},
+	}
+	for _, test := range tests {
+		err := toError(status.Error(test.code, "message"))
+		if !errors.Is(err, test.err) {
+			t.Fatalf("expected %s to match '%v'", test.code, test.err)
+		}
+		if status.Code(err) != test.code {
+			t.Fatalf("expected the status code %s to be kept", test.code)
+		}
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.Message() != "message" {
+			t.Fatal("expected a status error holding the message")
+		}
+	}
+	if err := toError(status.Error(codes.Internal, "")); errors.Unwrap(err) != nil {
+		t.Fatal("internal errors should not match a sentinel error")
+	}
+}
+
+func TestToError_Retry(t *testing.T) {
+	err := toError(&RetryError{Attempts: 3, Err: status.Error(codes.Unavailable, "")})
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 {
+		t.Fatal("expected the retry error to be kept")
+	}
+	if !errors.Is(err, ErrUnavailable) || status.Code(err) != codes.Unavailable {
+		t.Fatal("expected the last attempt's error to be mapped")
+	}
+}
+
+func TestGetAnchorType_Invalid(t *testing.T) {
+	if _, err := getAnchorType("NOT_AN_ANCHOR"); !errors.Is(err, ErrInvalidAnchorType) {
+		t.Fatalf("expected invalid anchor type, got %v", err)
+	}
+	if _, err := getAnchorType(1); !errors.Is(err, ErrInvalidAnchorType) {
+		t.Fatalf("expected invalid anchor type, got %v", err)
+	}
+}
+
+func TestClient_WatchProof_BatchFailed(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_ERROR, Error: "insufficient funds"}}},
+		},
+	}}
+	sub, err := client.WatchProof(context.Background(), "ab:1", Anchor_ETH, SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := <-sub.Updates()
+	var batchErr *BatchError
+	if !errors.Is(u.Err, ErrBatchFailed) || !errors.As(u.Err, &batchErr) || batchErr.Batch.GetError() != "insufficient funds" {
+		t.Fatalf("expected a batch error, got %v", u.Err)
+	}
+}
+
+func TestClient_GetProof_InvalidID(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{}}
+	if _, err := client.GetProof(context.Background(), "hash", Anchor_ETH); !errors.Is(err, ErrInvalidProofID) {
+		t.Fatalf("expected invalid proof ID, got %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package anchor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ethTrie represents the decoded data of an ETH_TRIE proof. The trie nodes are RLP encoded
+// Ethereum Merkle Patricia Trie nodes, with the first node being the root of the trie.
+type ethTrie struct {
+	AnchorType  string   `json:"anchorType"`
+	TxnId       string   `json:"txnId"`
+	TxnUri      string   `json:"txnUri"`
+	BlockTime   uint64   `json:"blockTime"`
+	BlockNumber uint64   `json:"blockNumber"`
+	TrieNodes   [][]byte `json:"trieNodes"`
+}
+
+// ethTrieLeaf represents a key/value pair found in a trie.
+type ethTrieLeaf struct {
+	key   []byte
+	value []byte
+}
+
+// VerifyETHTrie rebuilds the trie node hashes of the decoded ETH_TRIE proof data, checks the
+// given hash is a key of the trie, and returns the trie root expected in the Ethereum transaction.
+// Signed proofs nest the data trie inside a trie holding the signature, in which case the
+// hash is searched for recursively.
+func VerifyETHTrie(data map[string]interface{}, hash string) (*OfflineVerification, error) {
+	trie, err := toETHTrie(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("proof hash '%s' is not hex", hash)
+	}
+	nodes, root, err := trie.nodes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := searchETHTrie(root, key, nodes, 0); err != nil {
+		return nil, err
+	}
+	return &OfflineVerification{
+		Hash: hash,
+		Root: root,
+		Anchors: []*VerifiedAnchor{{
+			Type:     trie.AnchorType,
+			AnchorId: trie.TxnId,
+			Uris:     []string{trie.TxnUri},
+			Expected: root,
+		}},
+	}, nil
+}
+
+// Converts the decoded proof data to the ETH trie. The data is normalized through JSON so data
+// loaded from an exported file (where the nodes are base64 strings) is also supported.
+func toETHTrie(data map[string]interface{}) (*ethTrie, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	trie := &ethTrie{}
+	if err := json.Unmarshal(b, trie); err != nil {
+		return nil, err
+	}
+	if len(trie.TrieNodes) == 0 {
+		return nil, errors.New("proof data is missing 'trieNodes'")
+	}
+	return trie, nil
+}
+
+// Decodes every node of the trie and indexes them by their hex encoded keccak256 hash. The hash
+// of the first node (the root) is also returned.
+func (t *ethTrie) nodes() (map[string][]interface{}, string, error) {
+	nodes := make(map[string][]interface{})
+	root := ""
+	for i, n := range t.TrieNodes {
+		item, rest, err := decodeRLP(n)
+		if err != nil {
+			return nil, "", fmt.Errorf("trie node %d: %s", i, err.Error())
+		}
+		list, ok := item.([]interface{})
+		if !ok || len(rest) != 0 {
+			return nil, "", fmt.Errorf("trie node %d is not a valid node", i)
+		}
+		h := hex.EncodeToString(keccak256(n))
+		nodes[h] = list
+		if i == 0 {
+			root = h
+		}
+	}
+	return nodes, root, nil
+}
+
+// Searches the trie with the given root for the key, returning the roots of each trie traversed
+// to find it. Leaves keyed by the root of another trie in the proof are searched recursively.
+func searchETHTrie(root string, key []byte, nodes map[string][]interface{}, depth int) ([]string, error) {
+	if depth > 8 {
+		return nil, errors.New("trie nesting is too deep")
+	}
+	leaves := make([]*ethTrieLeaf, 0)
+	if err := collectETHTrieLeaves(nodes[root], []byte{}, nodes, &leaves); err != nil {
+		return nil, err
+	}
+	for _, l := range leaves {
+		if bytes.Equal(l.key, key) {
+			return []string{root}, nil
+		}
+	}
+	for _, l := range leaves {
+		sub := hex.EncodeToString(l.key)
+		if _, ok := nodes[sub]; !ok || sub == root {
+			continue
+		}
+		if roots, err := searchETHTrie(sub, key, nodes, depth+1); err == nil {
+			return append([]string{root}, roots...), nil
+		}
+	}
+	return nil, fmt.Errorf("hash '%x' not found in trie", key)
+}
+
+// Walks the trie node collecting every leaf. Nodes referenced by hash but not included in the
+// proof are skipped.
+func collectETHTrieLeaves(node []interface{}, prefix []byte, nodes map[string][]interface{}, leaves *[]*ethTrieLeaf) error {
+	if len(prefix) > 256 {
+		return errors.New("trie is too deep")
+	}
+	switch len(node) {
+	case 17:
+		// Branch node
+		for i := 0; i < 16; i++ {
+			child, err := resolveETHTrieNode(node[i], nodes)
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				continue
+			}
+			p := append(append([]byte{}, prefix...), byte(i))
+			if err := collectETHTrieLeaves(child, p, nodes, leaves); err != nil {
+				return err
+			}
+		}
+		if v, ok := node[16].([]byte); ok && len(v) > 0 {
+			addETHTrieLeaf(prefix, v, leaves)
+		}
+	case 2:
+		// Leaf or extension node
+		path, ok := node[0].([]byte)
+		if !ok || len(path) == 0 {
+			return errors.New("invalid trie node path")
+		}
+		nibbles := toNibbles(path)
+		flag := nibbles[0]
+		if flag > 3 {
+			return fmt.Errorf("invalid trie node path flag %d", flag)
+		}
+		if flag%2 == 0 {
+			nibbles = nibbles[2:]
+		} else {
+			nibbles = nibbles[1:]
+		}
+		p := append(append([]byte{}, prefix...), nibbles...)
+		if flag >= 2 {
+			v, ok := node[1].([]byte)
+			if !ok {
+				return errors.New("invalid trie leaf value")
+			}
+			addETHTrieLeaf(p, v, leaves)
+			return nil
+		}
+		child, err := resolveETHTrieNode(node[1], nodes)
+		if err != nil {
+			return err
+		}
+		if child != nil {
+			return collectETHTrieLeaves(child, p, nodes, leaves)
+		}
+	default:
+		return fmt.Errorf("invalid trie node with %d items", len(node))
+	}
+	return nil
+}
+
+// Resolves a child reference, which is either the hash of a node or an embedded node.
+func resolveETHTrieNode(ref interface{}, nodes map[string][]interface{}) ([]interface{}, error) {
+	switch r := ref.(type) {
+	case []interface{}:
+		return r, nil
+	case []byte:
+		if len(r) == 0 {
+			return nil, nil
+		}
+		if len(r) != 32 {
+			return nil, errors.New("invalid trie node reference")
+		}
+		return nodes[hex.EncodeToString(r)], nil
+	default:
+		return nil, errors.New("invalid trie node reference")
+	}
+}
+
+// Adds the leaf if the nibble path can be represented as bytes.
+func addETHTrieLeaf(nibbles []byte, value []byte, leaves *[]*ethTrieLeaf) {
+	if len(nibbles)%2 != 0 {
+		return
+	}
+	key := make([]byte, len(nibbles)/2)
+	for i := range key {
+		key[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+	*leaves = append(*leaves, &ethTrieLeaf{key: key, value: value})
+}
+
+// Splits the bytes into nibbles.
+func toNibbles(b []byte) []byte {
+	n := make([]byte, 0, len(b)*2)
+	for _, v := range b {
+		n = append(n, v>>4, v&0x0f)
+	}
+	return n
+}
+
+// Returns the keccak256 hash used by Ethereum.
+func keccak256(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// Decodes a single RLP item, returning the item and any remaining bytes. Strings are returned as
+// []byte and lists as []interface{}.
+func decodeRLP(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("rlp: unexpected end of input")
+	}
+	prefix := b[0]
+	switch {
+	case prefix < 0x80:
+		return b[:1], b[1:], nil
+	case prefix <= 0xb7:
+		return rlpSlice(b, 1, int(prefix-0x80))
+	case prefix < 0xc0:
+		size, err := rlpLength(b, int(prefix-0xb7))
+		if err != nil {
+			return nil, nil, err
+		}
+		return rlpSlice(b, 1+int(prefix-0xb7), size)
+	case prefix <= 0xf7:
+		return rlpList(b, 1, int(prefix-0xc0))
+	default:
+		size, err := rlpLength(b, int(prefix-0xf7))
+		if err != nil {
+			return nil, nil, err
+		}
+		return rlpList(b, 1+int(prefix-0xf7), size)
+	}
+}
+
+// Reads the big endian length following the prefix byte.
+func rlpLength(b []byte, n int) (int, error) {
+	if n > 4 || len(b) < 1+n {
+		return 0, errors.New("rlp: invalid length")
+	}
+	size := 0
+	for _, v := range b[1 : 1+n] {
+		size = size<<8 | int(v)
+	}
+	return size, nil
+}
+
+func rlpSlice(b []byte, offset int, size int) (interface{}, []byte, error) {
+	if len(b) < offset+size {
+		return nil, nil, errors.New("rlp: unexpected end of input")
+	}
+	return b[offset : offset+size], b[offset+size:], nil
+}
+
+func rlpList(b []byte, offset int, size int) (interface{}, []byte, error) {
+	if len(b) < offset+size {
+		return nil, nil, errors.New("rlp: unexpected end of input")
+	}
+	payload := b[offset : offset+size]
+	items := make([]interface{}, 0)
+	for len(payload) > 0 {
+		item, rest, err := decodeRLP(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+		payload = rest
+	}
+	return items, b[offset+size:], nil
+}
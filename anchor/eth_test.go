@@ -0,0 +1,87 @@
+package anchor
+
+import (
+	"testing"
+)
+
+const (
+	ethTrieData       = "eJxqW5WYl5yRXxRSWZC62DXEY2lJRZ5nyk0HA7OUNMskcwOTZEvjtLREQ6MkA4u0ZFODFGNzM1Mzs8QkE8NkgyRzI0PjZDMLc3ODVAvjRKMky5TkRJPURHOTtGUlFXmhRZk3kzJKSgqKrfT1izLzslOTKvVSSzJSi4qTE/P0MvP1Syr0DSootWplUk5+cnZIZm7qeQYGBoaEWEXJ1WAhv9LcpNQisCBD3cLolSVFmal++SmpxROPaL5YqKDzyXdX/IbFfGovrLWO7nw0T1pMLkZ+uVNcMYtx0uTuGSqtvt7OTt6AAAAA///4m2Mk"
+	ethTrieSignedData = "eJxqW5WYl5yRXxRSWZC62DXEY2lJRZ5nyk0HA1Mz09Qko5QUg2SLZPNUC1MLM5NUS2PzJEtLS/MksxQDC4M0M8s0Q4PkNHPDxBSTJGNDQ+M0y0RzA/NksxTzxGUlFXmhRZk3kzJKSgqKrfT1izLzslOTKvVSSzJSi4qTE/P0MvP1Syr0DSootWplUk5+cnZIZm7qeQYGBoaEWOXg1WAhv9LcpNQisCBD3cLGlSVFmal++SmpxVOOBP8IbICABTV/Y1rfR3uelcie8eibm5lEteLq2U0de5eXMTbtFjQ6vwasiHnz3IdfmRg/r19Zn3/roWr8fbWVIptPXfvjY8iwb+bfB/kgRUc0ni8w6f/IHd3jd1Z373t1qf6jLBYFjze8ZVrq//Pca9XT7okfWn29nZ28jzJy/2TkaDHOTLfayciwdMsWk9JjPHbbb5va260/0Wx6OutlWR/PdLfvMuX3V1akW63K3fnC/onVbM9bJasUU42kfr5nFN3gX800/daVkN97b1qe0tsiWLGd165u8Xln9nPfT8ovSxFqf5wrIK07uejmk5WXDxf39a53EKr5+/1Un2V/hFB1nvl8RbPAtTt8n8guK7HZv6R3969nE/RX2XgmTboa9fRqhs5G66anCnwp81YXCC6K2X3Zbm71m20+/5ao8lmb/pbVOjRHTGlKwMzKu9HT7wpnmzCzsBh/33KoYm1LqUmr/Y7036yZm9Qj5ewmXks5+uqQaX3DtkSGRzcyLkzPfPNNUJJf3dw9dfP9rIy+RTu2BGqf3250RPPFQgWdT7674jcs5lN7Ya11dOejedJicjHyy53iilmMkyZ3z1CBBBwgAAD//0CEJ0A="
+
+	ethTrieHash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+)
+
+func TestVerifyETHTrie(t *testing.T) {
+	data, err := DecodeProof(ethTrieData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifyETHTrie(data, ethTrieHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Root != "c48ff10b5b8c4ecd2dbdef271a8fc5043870e3b0ed02a54ff9ceeb25cb4761f0" {
+		t.Fatalf("unexpected root '%s'", res.Root)
+	}
+	a := res.Anchors[0]
+	if a.Type != "ETH" || a.AnchorId != "06df9b704c93ffa12b08fc50d376566ab41c0b7213c68770e83a2b9dca4ea74f" {
+		t.Fatal("unexpected anchor")
+	}
+}
+
+func TestVerifyETHTrie_Signed(t *testing.T) {
+	data, err := DecodeProof(ethTrieSignedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifyETHTrie(data, ethTrieHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Root != "b652cc478c320943e4ef955eecd17ba9450ced35639bd38eeba24287556fa811" {
+		t.Fatalf("unexpected root '%s'", res.Root)
+	}
+}
+
+func TestVerifyETHTrie_WrongHash(t *testing.T) {
+	data, err := DecodeProof(ethTrieSignedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyETHTrie(data, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"); err == nil {
+		t.Fatal("expected hash not found error")
+	}
+}
+
+func TestVerifyOffline_ETHTrie(t *testing.T) {
+	data, err := DecodeProof(ethTrieData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &AnchorProof{
+		Format: Proof_ETH_TRIE,
+		Hash:   ethTrieHash,
+		Data:   data,
+	}
+	if _, err := VerifyOffline(p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodeRLP(t *testing.T) {
+	// ["cat", "dog"]
+	item, rest, err := decodeRLP([]byte{0xc8, 0x83, 'c', 'a', 't', 0x83, 'd', 'o', 'g'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, ok := item.([]interface{})
+	if !ok || len(list) != 2 || len(rest) != 0 {
+		t.Fatal("expected a list of 2 items")
+	}
+	if string(list[0].([]byte)) != "cat" || string(list[1].([]byte)) != "dog" {
+		t.Fatal("unexpected list items")
+	}
+	if _, _, err := decodeRLP([]byte{0x83, 'c'}); err == nil {
+		t.Fatal("expected truncated input error")
+	}
+}
@@ -0,0 +1,141 @@
+package batchserver
+
+import (
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"github.com/SouthbankSoftware/provendb-sdk-go/merkle"
+)
+
+// The label of the branch holding the path from a hash to its batch root.
+const batchBranchLabel = "pdb_batch_branch"
+
+// Batch holds the state of a single batch. Batches of a server must only be read or updated with
+// the server locked.
+type Batch struct {
+	Id         string
+	AnchorType anchor.Anchor_Type
+	Status     anchor.Batch_Status
+	Err        string
+	Hashes     []string
+	Root       string // the root of the batch tree, set by BuildTree
+	TxnId      string // the anchor transaction of the root, set by Server.Submit
+
+	CreatedAt   time.Time
+	FlushedAt   time.Time
+	StartedAt   time.Time
+	SubmittedAt time.Time
+	FinalizedAt time.Time
+
+	index map[string]bool
+	tree  *merkle.Tree
+}
+
+// NewBatch creates a new BATCHING batch.
+func NewBatch(id string, anchorType anchor.Anchor_Type, now time.Time) *Batch {
+	return &Batch{
+		Id:         id,
+		AnchorType: anchorType,
+		Status:     anchor.Batch_BATCHING,
+		CreatedAt:  now,
+		index:      make(map[string]bool),
+	}
+}
+
+// Add adds the hash to the batch, ignoring duplicates.
+func (b *Batch) Add(hash string) {
+	if b.index[hash] {
+		return
+	}
+	b.index[hash] = true
+	b.Hashes = append(b.Hashes, hash)
+}
+
+// Contains returns whether the hash was added to the batch.
+func (b *Batch) Contains(hash string) bool {
+	return b.index[hash]
+}
+
+// BuildTree builds the merkle tree of the batch's hashes and sets the batch root.
+func (b *Batch) BuildTree() {
+	builder := merkle.NewBuilder(merkle.SHA256)
+	for _, h := range b.Hashes {
+		builder.AddRaw(h, h)
+	}
+	b.tree = builder.Build()
+	b.Root = b.tree.GetRoot()
+}
+
+// Returns the proof of the hash. The proof data is only available once the batch is submitted.
+func (b *Batch) proof(name string, hash string, withBatch bool) (*anchor.Proof, error) {
+	p := &anchor.Proof{
+		Hash:        hash,
+		BatchId:     b.Id,
+		AnchorType:  b.AnchorType,
+		BatchStatus: b.Status,
+		Format:      anchor.Proof_CHP_PATH,
+	}
+	if b.TxnId != "" {
+		data, err := b.proofData(name, hash)
+		if err != nil {
+			return nil, err
+		}
+		p.Data = data
+	}
+	if withBatch {
+		p.Batch = b.toBatch()
+	}
+	return p, nil
+}
+
+// Builds the encoded CHP_PATH proof data of the hash, from the hash to the batch root and on to
+// the anchor transaction.
+func (b *Batch) proofData(name string, hash string) (string, error) {
+	at := b.SubmittedAt.UTC().Format(time.RFC3339)
+	path := &anchor.CHPPath{
+		Context:             "https://w3id.org/chainpoint/v3",
+		Type:                "Chainpoint",
+		Hash:                b.Root,
+		HashIdNode:          b.Id,
+		HashSubmittedNodeAt: at,
+		HashIdCore:          b.Id,
+		HashSubmittedCoreAt: at,
+		Branches: []*anchor.CHPBranch{{
+			Label: "pdb_" + name + "_anchor_branch",
+			Ops: []*anchor.CHPOp{{
+				Anchors: []*anchor.CHPAnchor{{
+					Type:     "cal",
+					AnchorId: b.TxnId,
+					Uris:     []string{name + "://" + b.AnchorType.String() + "/" + b.TxnId},
+				}},
+			}},
+		}},
+	}
+	data, err := path.Map()
+	if err != nil {
+		return "", err
+	}
+	root := &anchor.AnchorProof{Format: anchor.Proof_CHP_PATH, Data: data}
+	p, err := b.tree.AddPathToProof(root, hash, batchBranchLabel)
+	if err != nil {
+		return "", err
+	}
+	return anchor.EncodeProof(p.Data)
+}
+
+func (b *Batch) toBatch() *anchor.Batch {
+	return &anchor.Batch{
+		Id:          b.Id,
+		AnchorType:  b.AnchorType,
+		ProofFormat: anchor.Proof_CHP_PATH,
+		Status:      b.Status,
+		Error:       b.Err,
+		Size:        int64(len(b.Hashes)),
+		Hash:        b.Root,
+		CreatedAt:   timestamp(b.CreatedAt),
+		FlushedAt:   timestamp(b.FlushedAt),
+		StartedAt:   timestamp(b.StartedAt),
+		SubmittedAt: timestamp(b.SubmittedAt),
+		FinalizedAt: timestamp(b.FinalizedAt),
+	}
+}
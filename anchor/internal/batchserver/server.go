@@ -0,0 +1,425 @@
+// Package batchserver implements the anchor service shared by the anchortest and local services.
+//
+// The server batches submitted hashes into merkle trees, serves CHP_PATH proofs from the hashes
+// to the anchored batch roots, delivers batch status transitions to subscribers and verifies
+// proofs against the confirmed batches. When and how batches are anchored is left to the service
+// embedding it, which moves the batches through their statuses with Transition and Submit.
+package batchserver
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Options represents the server options.
+type Options struct {
+	// The name of the anchoring service, used in the labels and URIs of the proofs' anchors.
+	Name string
+	// The anchors served. All are running initially.
+	Anchors []anchor.Anchor_Type
+	// The number of hashes which flushes a batch immediately. Zero disables the limit.
+	BatchSize int
+	// Returns the current time.
+	Now func() time.Time
+	// Returns the ID of a new batch.
+	NewBatchId func() string
+	// Called with the server locked when a batch stops accepting hashes because it is full, it was
+	// submitted with SkipBatching, or FlushOpen was called. The batch is no longer open.
+	Flush func(b *Batch)
+}
+
+// Server implements anchor.AnchorServiceServer over the batches.
+type Server struct {
+	anchor.UnimplementedAnchorServiceServer
+
+	opts *Options
+
+	mu          sync.Mutex
+	anchors     map[anchor.Anchor_Type]*anchor.Anchor
+	batches     map[string]*Batch
+	order       []*Batch
+	txns        map[string]*Batch
+	open        map[anchor.Anchor_Type]*Batch
+	subscribers map[*subscriber]bool
+	subscribed  chan struct{}
+	closed      bool
+}
+
+// New creates a new server.
+func New(o *Options) *Server {
+	s := &Server{
+		opts:        o,
+		anchors:     make(map[anchor.Anchor_Type]*anchor.Anchor),
+		batches:     make(map[string]*Batch),
+		txns:        make(map[string]*Batch),
+		open:        make(map[anchor.Anchor_Type]*Batch),
+		subscribers: make(map[*subscriber]bool),
+		subscribed:  make(chan struct{}),
+	}
+	for _, t := range o.Anchors {
+		s.anchors[t] = &anchor.Anchor{
+			Type:             t,
+			Status:           anchor.Anchor_RUNNING,
+			SupportedFormats: []anchor.Proof_Format{anchor.Proof_CHP_PATH},
+		}
+	}
+	return s
+}
+
+// Lock locks the server, so its batches can be updated.
+func (s *Server) Lock() {
+	s.mu.Lock()
+}
+
+// Unlock unlocks the server.
+func (s *Server) Unlock() {
+	s.mu.Unlock()
+}
+
+// Close rejects any further submission.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+// Restore adds a batch submitted before the server was created, e.g. read from storage.
+func (s *Server) Restore(b *Batch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches[b.Id] = b
+	s.order = append(s.order, b)
+	if b.TxnId != "" {
+		s.txns[b.TxnId] = b
+	}
+}
+
+// Batches returns the batches in the order they were created. The server must be locked.
+func (s *Server) Batches() []*Batch {
+	return s.order
+}
+
+// Batch returns the batch with the given ID, or nil if not found.
+func (s *Server) Batch(batchId string) *anchor.Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.batches[batchId]; ok {
+		return b.toBatch()
+	}
+	return nil
+}
+
+// FlushOpen flushes every open batch. The server must be locked.
+func (s *Server) FlushOpen() {
+	for _, t := range s.opts.Anchors {
+		if b, ok := s.open[t]; ok {
+			delete(s.open, t)
+			s.opts.Flush(b)
+		}
+	}
+}
+
+// Transition moves the batch to the status at the given time, recording the time of the status
+// and delivering the batch to the subscribers. A batch leaving BATCHING is no longer open. The
+// server must be locked.
+func (s *Server) Transition(b *Batch, st anchor.Batch_Status, at time.Time) {
+	b.Status = st
+	switch st {
+	case anchor.Batch_QUEUING:
+		b.FlushedAt = at
+	case anchor.Batch_PROCESSING:
+		b.StartedAt = at
+	case anchor.Batch_PENDING:
+		b.SubmittedAt = at
+	case anchor.Batch_CONFIRMED, anchor.Batch_ERROR:
+		b.FinalizedAt = at
+	}
+	if st != anchor.Batch_BATCHING && s.open[b.AnchorType] == b {
+		delete(s.open, b.AnchorType)
+	}
+	s.publish(b)
+}
+
+// Submit records the batch root was submitted to the anchor in the transaction, moving the batch
+// to PENDING. The tree must be built. The server must be locked.
+func (s *Server) Submit(b *Batch, txnId string, at time.Time) {
+	b.TxnId = txnId
+	s.txns[txnId] = b
+	s.Transition(b, anchor.Batch_PENDING, at)
+}
+
+// Fail moves the batch to ERROR with the given message. The server must be locked.
+func (s *Server) Fail(b *Batch, message string, at time.Time) {
+	b.Err = message
+	s.Transition(b, anchor.Batch_ERROR, at)
+}
+
+// FailBatch moves the batch with the given ID to ERROR with the given message.
+func (s *Server) FailBatch(batchId string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchId]
+	if !ok {
+		return fmt.Errorf("batch '%s' not found", batchId)
+	}
+	s.Fail(b, message, s.opts.Now())
+	return nil
+}
+
+// SetAnchorStatus sets the status of the anchor. Submissions to a stopped anchor are rejected.
+func (s *Server) SetAnchorStatus(anchorType anchor.Anchor_Type, st anchor.Anchor_Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.anchors[anchorType]; ok {
+		a.Status = st
+	}
+}
+
+// AwaitSubscribers blocks until at least n batch subscriptions are open.
+func (s *Server) AwaitSubscribers(ctx context.Context, n int) error {
+	for {
+		s.mu.Lock()
+		count := len(s.subscribers)
+		subscribed := s.subscribed
+		s.mu.Unlock()
+		if count >= n {
+			return nil
+		}
+		select {
+		case <-subscribed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// GetAnchors implements anchor.AnchorServiceServer.
+func (s *Server) GetAnchors(_ *emptypb.Empty, stream anchor.AnchorService_GetAnchorsServer) error {
+	s.mu.Lock()
+	anchors := make([]*anchor.Anchor, 0, len(s.opts.Anchors))
+	for _, t := range s.opts.Anchors {
+		anchors = append(anchors, copyAnchor(s.anchors[t]))
+	}
+	s.mu.Unlock()
+	for _, a := range anchors {
+		if err := stream.Send(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAnchor implements anchor.AnchorServiceServer.
+func (s *Server) GetAnchor(_ context.Context, req *anchor.AnchorRequest) (*anchor.Anchor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.anchors[req.GetType()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "anchor '%s' not found", req.GetType())
+	}
+	return copyAnchor(a), nil
+}
+
+// SubmitProof implements anchor.AnchorServiceServer.
+func (s *Server) SubmitProof(_ context.Context, req *anchor.SubmitProofRequest) (*anchor.Proof, error) {
+	hash := req.GetHash()
+	if _, err := hex.DecodeString(hash); err != nil || hash == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid hash '%s'", hash)
+	}
+	if req.GetFormat() != anchor.Proof_CHP_PATH {
+		return nil, status.Errorf(codes.InvalidArgument, "proof format '%s' not supported", req.GetFormat())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, status.Error(codes.Unavailable, "service is closed")
+	}
+	a, ok := s.anchors[req.GetAnchorType()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "anchor '%s' not found", req.GetAnchorType())
+	}
+	if a.Status != anchor.Anchor_RUNNING {
+		return nil, status.Errorf(codes.FailedPrecondition, "anchor '%s' is stopped", a.Type)
+	}
+	b := s.open[a.Type]
+	if b == nil || req.GetSkipBatching() {
+		b = s.newBatch(a.Type, !req.GetSkipBatching())
+	}
+	b.Add(hash)
+	if req.GetSkipBatching() || (s.opts.BatchSize > 0 && len(b.Hashes) >= s.opts.BatchSize) {
+		delete(s.open, a.Type)
+		s.opts.Flush(b)
+	}
+	return s.proof(b, hash, false)
+}
+
+// GetProof implements anchor.AnchorServiceServer.
+func (s *Server) GetProof(_ context.Context, req *anchor.ProofRequest) (*anchor.Proof, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[req.GetBatchId()]
+	if !ok || b.AnchorType != req.GetAnchorType() || !b.Contains(req.GetHash()) {
+		return nil, status.Errorf(codes.NotFound, "proof '%s:%s' not found", req.GetHash(), req.GetBatchId())
+	}
+	return s.proof(b, req.GetHash(), req.GetWithBatch())
+}
+
+// GetBatch implements anchor.AnchorServiceServer.
+func (s *Server) GetBatch(_ context.Context, req *anchor.BatchRequest) (*anchor.Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[req.GetBatchId()]
+	if !ok || b.AnchorType != req.GetAnchorType() {
+		return nil, status.Errorf(codes.NotFound, "batch '%s' not found", req.GetBatchId())
+	}
+	return b.toBatch(), nil
+}
+
+// SubscribeBatches implements anchor.AnchorServiceServer. A subscription filtered on a batch
+// first receives the batch's current state.
+func (s *Server) SubscribeBatches(req *anchor.SubscribeBatchesRequest, stream anchor.AnchorService_SubscribeBatchesServer) error {
+	sub := &subscriber{
+		filter: req.GetFilter(),
+		notify: make(chan struct{}, 1),
+	}
+	s.mu.Lock()
+	if id := sub.filter.GetBatchId(); id != "" {
+		if b, ok := s.batches[id]; ok && b.AnchorType == sub.filter.GetAnchorType() {
+			sub.push(b.toBatch())
+		}
+	}
+	s.subscribers[sub] = true
+	close(s.subscribed)
+	s.subscribed = make(chan struct{})
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, sub)
+		s.mu.Unlock()
+	}()
+	for {
+		for _, b := range sub.pop() {
+			if err := stream.Send(b); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-sub.notify:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// VerifyProof implements anchor.AnchorServiceServer. The proof is verified if it evaluates to the
+// root of a confirmed batch.
+func (s *Server) VerifyProof(_ context.Context, req *anchor.VerifyProofRequest) (*anchor.VerifyProofReply, error) {
+	if req.GetFormat() != anchor.Proof_CHP_PATH {
+		return nil, status.Errorf(codes.InvalidArgument, "proof format '%s' not supported", req.GetFormat())
+	}
+	data, err := anchor.DecodeProof(req.GetData())
+	if err != nil {
+		return &anchor.VerifyProofReply{Error: err.Error()}, nil
+	}
+	res, err := anchor.VerifyCHPPath(data)
+	if err != nil {
+		return &anchor.VerifyProofReply{Error: err.Error()}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range res.Anchors {
+		b, ok := s.txns[a.AnchorId]
+		if !ok || b.AnchorType != req.GetAnchorType() {
+			return &anchor.VerifyProofReply{Error: fmt.Sprintf("transaction '%s' not found", a.AnchorId)}, nil
+		}
+		if b.Status != anchor.Batch_CONFIRMED {
+			return &anchor.VerifyProofReply{Error: fmt.Sprintf("transaction '%s' not confirmed", a.AnchorId)}, nil
+		}
+		if b.Root != a.Expected {
+			return &anchor.VerifyProofReply{Error: fmt.Sprintf("transaction '%s' does not match the proof", a.AnchorId)}, nil
+		}
+	}
+	return &anchor.VerifyProofReply{Verified: true, ProvenHash: res.Hash}, nil
+}
+
+// Creates a new batch, which is open unless it is only for a single hash.
+func (s *Server) newBatch(anchorType anchor.Anchor_Type, open bool) *Batch {
+	b := NewBatch(s.opts.NewBatchId(), anchorType, s.opts.Now())
+	s.batches[b.Id] = b
+	s.order = append(s.order, b)
+	if open {
+		s.open[anchorType] = b
+	}
+	s.publish(b)
+	return b
+}
+
+// Returns the proof of the hash in the batch.
+func (s *Server) proof(b *Batch, hash string, withBatch bool) (*anchor.Proof, error) {
+	p, err := b.proof(s.opts.Name, hash, withBatch)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return p, nil
+}
+
+// Delivers the batch to every matching subscriber.
+func (s *Server) publish(b *Batch) {
+	for sub := range s.subscribers {
+		f := sub.filter
+		if f.GetAnchorType() == b.AnchorType && (f.GetBatchId() == "" || f.GetBatchId() == b.Id) {
+			sub.push(b.toBatch())
+		}
+	}
+}
+
+// subscriber queues the batches of a subscription so publishing never blocks.
+type subscriber struct {
+	filter *anchor.BatchRequest
+	mu     sync.Mutex
+	queue  []*anchor.Batch
+	notify chan struct{}
+}
+
+func (s *subscriber) push(b *anchor.Batch) {
+	s.mu.Lock()
+	s.queue = append(s.queue, b)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) pop() []*anchor.Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.queue
+	s.queue = nil
+	return q
+}
+
+func copyAnchor(a *anchor.Anchor) *anchor.Anchor {
+	return &anchor.Anchor{
+		Type:             a.Type,
+		Status:           a.Status,
+		Error:            a.Error,
+		SupportedFormats: append([]anchor.Proof_Format{}, a.SupportedFormats...),
+	}
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
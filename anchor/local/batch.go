@@ -0,0 +1,31 @@
+package local
+
+import (
+	"fmt"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor/internal/batchserver"
+)
+
+// Restores a confirmed batch from its log entry, checking the entry's hashes build its root.
+func batchFromLog(e *LogEntry) (*batchserver.Batch, error) {
+	at, ok := anchor.Anchor_Type_value[e.AnchorType]
+	if !ok {
+		return nil, fmt.Errorf("log entry %d: %w '%s'", e.Index, anchor.ErrInvalidAnchorType, e.AnchorType)
+	}
+	b := batchserver.NewBatch(e.BatchId, anchor.Anchor_Type(at), e.CreatedAt)
+	for _, h := range e.Hashes {
+		b.Add(h)
+	}
+	b.BuildTree()
+	if b.Root != e.Root {
+		return nil, fmt.Errorf("log entry %d: hashes do not match the root '%s'", e.Index, e.Root)
+	}
+	b.TxnId = e.TxnId
+	b.Status = anchor.Batch_CONFIRMED
+	b.FlushedAt = e.FlushedAt
+	b.StartedAt = e.FlushedAt
+	b.SubmittedAt = e.SubmittedAt
+	b.FinalizedAt = e.SubmittedAt
+	return b, nil
+}
@@ -0,0 +1,138 @@
+package local
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// LogEntry is a batch anchored into the log. The transaction ID of each entry hashes all of the
+// entry's other fields, including the previous entry's transaction ID, so any modification of the
+// log is detected when it is opened.
+type LogEntry struct {
+	Index       int64     `json:"index"`
+	TxnId       string    `json:"txnId"` // sha256 of the JSON encoded entry without the transaction ID
+	Prev        string    `json:"prev"`  // the previous transaction ID
+	BatchId     string    `json:"batchId"`
+	AnchorType  string    `json:"anchorType"`
+	Root        string    `json:"root"`
+	Hashes      []string  `json:"hashes"`
+	CreatedAt   time.Time `json:"createdAt"`
+	FlushedAt   time.Time `json:"flushedAt"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+// Returns the transaction ID of the entry, hashing its content and the previous transaction ID.
+func (e *LogEntry) txnId() (string, error) {
+	c := *e
+	c.TxnId = ""
+	b, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// Log is an append-only log of anchored batches, stored as one JSON entry per line. A log without
+// a file is kept in memory only.
+type Log struct {
+	file    *os.File
+	entries []*LogEntry
+}
+
+// OpenLog opens the log at the given path, creating it if it doesn't exist, and verifies the
+// chain of entries. An empty path opens an in-memory log.
+func OpenLog(path string) (*Log, error) {
+	l := &Log{entries: make([]*LogEntry, 0)}
+	if path == "" {
+		return l, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		e := &LogEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("log entry %d: %s", len(l.entries), err.Error())
+		}
+		if err := l.check(e); err != nil {
+			f.Close()
+			return nil, err
+		}
+		l.entries = append(l.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	l.file = f
+	return l, nil
+}
+
+// Checks the entry follows the last entry of the log.
+func (l *Log) check(e *LogEntry) error {
+	prev := ""
+	if n := len(l.entries); n > 0 {
+		prev = l.entries[n-1].TxnId
+	}
+	if e.Index != int64(len(l.entries)) || e.Prev != prev {
+		return fmt.Errorf("log entry %d is not chained to the previous entry", len(l.entries))
+	}
+	id, err := e.txnId()
+	if err != nil {
+		return err
+	}
+	if e.TxnId != id {
+		return fmt.Errorf("log entry %d was modified", len(l.entries))
+	}
+	return nil
+}
+
+// Append chains the entry to the log and writes it, returning once it is synced to disk.
+func (l *Log) Append(e *LogEntry) error {
+	e.Index = int64(len(l.entries))
+	if e.Index > 0 {
+		e.Prev = l.entries[e.Index-1].TxnId
+	}
+	id, err := e.txnId()
+	if err != nil {
+		return err
+	}
+	e.TxnId = id
+	if l.file != nil {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := l.file.Write(append(b, '\n')); err != nil {
+			return err
+		}
+		if err := l.file.Sync(); err != nil {
+			return err
+		}
+	}
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+// Entries returns the entries of the log.
+func (l *Log) Entries() []*LogEntry {
+	return l.entries
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
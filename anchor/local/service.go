@@ -0,0 +1,189 @@
+// Package local provides a self-contained anchor service which anchors batches of hashes into a
+// local append-only log instead of a blockchain, so the full anchoring workflow can be run without
+// network access or an API key.
+//
+// Submitted hashes are batched into merkle trees, and every batch interval each open batch is
+// flushed and its root appended to the log, at which point the batch is CONFIRMED. Confirmed
+// batches are restored from the log on restart; batches not yet anchored are lost.
+package local
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor/internal/batchserver"
+	"google.golang.org/grpc"
+)
+
+// DefaultBatchInterval is the default time between each flush of the open batches.
+const DefaultBatchInterval = 10 * time.Second
+
+// Options represents the service options.
+type Options struct {
+	// The path of the log. Empty keeps the log in memory.
+	LogPath string
+	// The time between each flush of the open batches.
+	BatchInterval time.Duration
+	// The number of hashes which flushes a batch immediately. Zero disables the limit.
+	BatchSize int
+	// The anchor types served.
+	Anchors []anchor.Anchor_Type
+}
+
+// Option func.
+type Option func(*Options)
+
+// WithLogPath sets the path of the log.
+func WithLogPath(path string) Option {
+	return func(o *Options) {
+		o.LogPath = path
+	}
+}
+
+// WithBatchInterval sets the time between each flush of the open batches.
+func WithBatchInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.BatchInterval = d
+	}
+}
+
+// WithBatchSize sets the number of hashes which flushes a batch immediately.
+func WithBatchSize(size int) Option {
+	return func(o *Options) {
+		o.BatchSize = size
+	}
+}
+
+// WithAnchors sets the anchor types served.
+func WithAnchors(anchors ...anchor.Anchor_Type) Option {
+	return func(o *Options) {
+		o.Anchors = anchors
+	}
+}
+
+// Service implements the anchor service, anchoring into a local log.
+type Service struct {
+	opts *Options
+	core *batchserver.Server
+	log  *Log
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewService opens the log, restoring the batches anchored into it, and starts batching.
+func NewService(opts ...Option) (*Service, error) {
+	o := &Options{
+		BatchInterval: DefaultBatchInterval,
+		Anchors:       []anchor.Anchor_Type{anchor.Anchor_ETH, anchor.Anchor_HEDERA_MAINNET},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.BatchInterval <= 0 {
+		return nil, fmt.Errorf("invalid batch interval '%s'", o.BatchInterval)
+	}
+	l, err := OpenLog(o.LogPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		opts: o,
+		log:  l,
+		done: make(chan struct{}),
+	}
+	s.core = batchserver.New(&batchserver.Options{
+		Name:       "local",
+		Anchors:    o.Anchors,
+		BatchSize:  o.BatchSize,
+		Now:        time.Now,
+		NewBatchId: newBatchId,
+		Flush:      s.anchor,
+	})
+	for _, e := range l.Entries() {
+		b, err := batchFromLog(e)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		s.core.Restore(b)
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Register registers the service with the grpc server.
+func (s *Service) Register(server *grpc.Server) {
+	anchor.RegisterAnchorServiceServer(server, s.core)
+}
+
+// Close anchors the open batches and closes the log.
+func (s *Service) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.core.Close()
+		close(s.done)
+		s.wg.Wait()
+		s.core.Lock()
+		defer s.core.Unlock()
+		s.core.FlushOpen()
+		err = s.log.Close()
+	})
+	return err
+}
+
+// Flushes the open batches every batch interval.
+func (s *Service) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.opts.BatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.core.Lock()
+			s.core.FlushOpen()
+			s.core.Unlock()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Moves the flushed batch through each status, anchoring its root into the log. The core must
+// be locked.
+func (s *Service) anchor(b *batchserver.Batch) {
+	now := time.Now()
+	s.core.Transition(b, anchor.Batch_QUEUING, now)
+	s.core.Transition(b, anchor.Batch_PROCESSING, now)
+	b.BuildTree()
+	e := &LogEntry{
+		BatchId:     b.Id,
+		AnchorType:  b.AnchorType.String(),
+		Root:        b.Root,
+		Hashes:      b.Hashes,
+		CreatedAt:   b.CreatedAt,
+		FlushedAt:   b.FlushedAt,
+		SubmittedAt: now,
+	}
+	if err := s.log.Append(e); err != nil {
+		s.core.Fail(b, err.Error(), time.Now())
+		return
+	}
+	s.core.Submit(b, e.TxnId, now)
+	// The log entry is synced, so the batch is final.
+	s.core.Transition(b, anchor.Batch_CONFIRMED, time.Now())
+}
+
+// Generates a random batch ID.
+func newBatchId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
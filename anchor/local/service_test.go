@@ -0,0 +1,212 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testHash = "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f"
+
+// Serves the service over a buffered connection and connects a client to it.
+func newTestClient(t *testing.T, service *Service) *anchor.Client {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	service.Register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	client, err := anchor.Connect(anchor.WithAddress("bufconn"), anchor.WithInsecure(true), anchor.WithCredentials(""),
+		anchor.WithDialOptions(grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestService_SubmitProof(t *testing.T) {
+	service, err := NewService(WithBatchInterval(10 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	client := newTestClient(t, service)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p, err := client.SubmitProof(ctx, testHash, anchor.SubmitProofWithAwaitConfirmed(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != anchor.Batch_CONFIRMED {
+		t.Fatalf("expected CONFIRMED, got %s", p.Status)
+	}
+	res, err := client.VerifyProof(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Verified || res.ProvenHash != testHash {
+		t.Fatalf("proof not verified: %s", res.Error)
+	}
+}
+
+func TestService_BatchSize(t *testing.T) {
+	service, err := NewService(WithBatchInterval(time.Hour), WithBatchSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	client := newTestClient(t, service)
+	ctx := context.Background()
+	a, err := client.SubmitProof(ctx, strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := client.SubmitProof(ctx, strings.Repeat("b", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.BatchId != b.BatchId {
+		t.Fatal("expected the hashes to share a batch")
+	}
+	for _, p := range []*anchor.AnchorProof{a, b} {
+		p, err := client.GetProof(ctx, p.Id, anchor.Anchor_ETH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := anchor.VerifyOffline(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch, err := client.GetBatch(ctx, p.BatchId, anchor.Anchor_ETH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Status != anchor.Batch_CONFIRMED || res.Root != batch.GetHash() {
+			t.Fatal("expected the full batch to be anchored")
+		}
+	}
+}
+
+func TestService_Restart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchor.log")
+	service, err := NewService(WithLogPath(path), WithBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, service)
+	ctx := context.Background()
+	p, err := client.SubmitProof(ctx, testHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Closing anchors the open batch.
+	if err := service.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	service, err = NewService(WithLogPath(path), WithBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	client = newTestClient(t, service)
+	if p, err = client.GetProof(ctx, p.Id, anchor.Anchor_ETH); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != anchor.Batch_CONFIRMED {
+		t.Fatalf("expected CONFIRMED, got %s", p.Status)
+	}
+	res, err := client.VerifyProof(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Verified {
+		t.Fatalf("proof not verified after restart: %s", res.Error)
+	}
+}
+
+func TestOpenLog_Tampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchor.log")
+	l, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2021, 3, 25, 23, 41, 13, 0, time.UTC)
+	for _, root := range []string{strings.Repeat("a", 64), strings.Repeat("b", 64)} {
+		if err := l.Append(&LogEntry{Root: root, Hashes: []string{root}, CreatedAt: created}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+	if l, err = OpenLog(path); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if len(l.Entries()) != 2 || l.Entries()[1].Prev != l.Entries()[0].TxnId {
+		t.Fatal("expected the chained entries to be restored")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tamper := range [][2]string{
+		{`"root":"` + strings.Repeat("a", 64), `"root":"` + strings.Repeat("c", 64)},
+		{`"hashes":["` + strings.Repeat("b", 64), `"hashes":["` + strings.Repeat("c", 64)},
+		{`"createdAt":"2021-03-25T23:41:13Z"`, `"createdAt":"2021-03-25T23:41:14Z"`},
+	} {
+		tampered := strings.Replace(string(b), tamper[0], tamper[1], 1)
+		if tampered == string(b) {
+			t.Fatalf("'%s' not found in the log", tamper[0])
+		}
+		if err := ioutil.WriteFile(path, []byte(tampered), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenLog(path); err == nil {
+			t.Fatalf("expected the log with '%s' tampered to be rejected", tamper[0])
+		}
+	}
+}
+
+func TestNewService_RootMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchor.log")
+	l, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &LogEntry{AnchorType: anchor.Anchor_ETH.String(), Root: strings.Repeat("a", 64), Hashes: []string{testHash}}
+	if err := l.Append(e); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if _, err := NewService(WithLogPath(path)); err == nil {
+		t.Fatal("expected the entry not matching its root to be rejected")
+	}
+}
+
+func TestNewService_UnknownAnchorType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchor.log")
+	l, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &LogEntry{AnchorType: "UNKNOWN", Root: testHash, Hashes: []string{testHash}}
+	if err := l.Append(e); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if _, err := NewService(WithLogPath(path)); !errors.Is(err, anchor.ErrInvalidAnchorType) {
+		t.Fatalf("expected the entry of an unknown anchor type to be rejected, got %v", err)
+	}
+}
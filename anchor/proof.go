@@ -5,16 +5,73 @@ import (
 	"compress/zlib"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"time"
 
 	"github.com/vmihailenco/msgpack"
 )
 
 /**
  * AnchorProof is a represention of a Proof object with tasks such as decoding already
- * performed and enums represented as strings for readability.
+ * performed. Enums are marshalled to JSON as strings for readability.
  */
 type AnchorProof struct {
+	Id         string
+	AnchorType Anchor_Type
+	BatchId    string
+	Status     Batch_Status
+	Format     Proof_Format
+	Hash       string
+	Metadata   *BatchMetadata
+	Data       map[string]interface{}
+
+	encoded string // the data as received from the anchor service
+}
+
+// BatchMetadata holds the batch of a proof when it was retrieved.
+type BatchMetadata struct {
+	Id          string                 `json:"id"`
+	Status      Batch_Status           `json:"-"`
+	Error       string                 `json:"error,omitempty"`
+	Size        int64                  `json:"size"`
+	Hash        string                 `json:"hash,omitempty"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	FlushedAt   time.Time              `json:"flushedAt"`
+	StartedAt   time.Time              `json:"startedAt"`
+	SubmittedAt time.Time              `json:"submittedAt"`
+	FinalizedAt time.Time              `json:"finalizedAt"`
+	Data        map[string]interface{} `json:"-"` // the anchor specific data of the batch
+}
+
+// Converts the batch to its metadata, decoding the batch data.
+func newBatchMetadata(b *Batch) (*BatchMetadata, error) {
+	m := &BatchMetadata{
+		Id:          b.GetId(),
+		Status:      b.GetStatus(),
+		Error:       b.GetError(),
+		Size:        b.GetSize(),
+		Hash:        b.GetHash(),
+		CreatedAt:   toTime(b.GetCreatedAt()),
+		FlushedAt:   toTime(b.GetFlushedAt()),
+		StartedAt:   toTime(b.GetStartedAt()),
+		SubmittedAt: toTime(b.GetSubmittedAt()),
+		FinalizedAt: toTime(b.GetFinalizedAt()),
+	}
+	if b.GetData() != "" {
+		if err := json.Unmarshal([]byte(b.GetData()), &m.Data); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// The JSON form of the proof. The enums are stored as strings, and the batch data as 'Metadata',
+// as in proofs stored before the fields were typed. The rest of the metadata is stored as
+// 'Batch', and the data as received from the anchor service as 'Encoded', which are both missing
+// from those proofs.
+type anchorProofJSON struct {
 	Id         string
 	AnchorType string
 	BatchId    string
@@ -22,23 +79,91 @@ type AnchorProof struct {
 	Format     string
 	Hash       string
 	Metadata   interface{}
+	Batch      *batchMetadataJSON `json:",omitempty"`
 	Data       map[string]interface{}
+	Encoded    string `json:",omitempty"`
+}
+
+type batchMetadataJSON struct {
+	*BatchMetadata
+	Status string `json:"status"`
+}
+
+// MarshalJSON marshals the proof, with the enums as strings. It has a value receiver so proofs are
+// marshalled the same whether or not they are held by pointer.
+func (a AnchorProof) MarshalJSON() ([]byte, error) {
+	j := &anchorProofJSON{
+		Id:         a.Id,
+		AnchorType: a.AnchorType.String(),
+		BatchId:    a.BatchId,
+		Status:     a.Status.String(),
+		Format:     a.Format.String(),
+		Hash:       a.Hash,
+		Data:       a.Data,
+		Encoded:    a.encoded,
+	}
+	if a.Metadata != nil {
+		j.Metadata = a.Metadata.Data
+		j.Batch = &batchMetadataJSON{BatchMetadata: a.Metadata, Status: a.Metadata.Status.String()}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON unmarshals the proof, including proofs stored before the fields were typed.
+func (a *AnchorProof) UnmarshalJSON(b []byte) error {
+	j := &anchorProofJSON{}
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	p := AnchorProof{
+		Id:      j.Id,
+		BatchId: j.BatchId,
+		Hash:    j.Hash,
+		Data:    j.Data,
+		encoded: j.Encoded,
+	}
+	// Empty enums are left as their zero value.
+	var err error
+	if j.AnchorType != "" {
+		if p.AnchorType, err = getAnchorType(j.AnchorType); err != nil {
+			return err
+		}
+	}
+	if j.Status != "" {
+		if p.Status, err = getBatchStatus(j.Status); err != nil {
+			return err
+		}
+	}
+	if j.Format != "" {
+		if p.Format, err = getProofFormat(j.Format); err != nil {
+			return err
+		}
+	}
+	// Proofs stored before the fields were typed may hold any batch data, only objects are kept.
+	data, _ := j.Metadata.(map[string]interface{})
+	if j.Batch != nil || data != nil {
+		p.Metadata = &BatchMetadata{}
+		if j.Batch != nil && j.Batch.BatchMetadata != nil {
+			p.Metadata = j.Batch.BatchMetadata
+			if j.Batch.Status != "" {
+				if p.Metadata.Status, err = getBatchStatus(j.Batch.Status); err != nil {
+					return err
+				}
+			}
+		}
+		p.Metadata.Data = data
+	}
+	*a = p
+	return nil
 }
 
 func (a *AnchorProof) FromProof(proof *Proof) error {
 	if proof.GetBatch() != nil {
-		if proof.GetBatch().GetData() != "" {
-			var data interface{}
-			if e := json.Unmarshal([]byte(proof.GetBatch().GetData()), &data); e != nil {
-				fmt.Print(e.Error())
-				return e
-			}
-			// var meta map[string]interface{}
-			// if e := json.Unmarshal([]byte(data), &meta); e != nil {
-			// 	return e
-			// }
-			a.Metadata = data
+		m, err := newBatchMetadata(proof.GetBatch())
+		if err != nil {
+			return err
 		}
+		a.Metadata = m
 	}
 	if proof.GetData() != "" {
 		data, err := DecodeProof(proof.GetData())
@@ -46,30 +171,180 @@ func (a *AnchorProof) FromProof(proof *Proof) error {
 			return err
 		}
 		a.Data = data
+		a.encoded = proof.GetData()
 	}
 	a.Id = generateProofId(proof.GetHash(), proof.GetBatchId())
-	a.AnchorType = proof.GetAnchorType().String()
-	a.Format = proof.GetFormat().String()
+	a.AnchorType = proof.GetAnchorType()
+	a.Format = proof.GetFormat()
 	a.BatchId = proof.GetBatchId()
 	a.Hash = proof.GetHash()
-	a.Status = proof.GetBatchStatus().String()
+	a.Status = proof.GetBatchStatus()
 	return nil
 }
 
+// EncodeData encodes the proof data into the wire format. The data received from the anchor
+// service, which is kept when the proof is marshalled to JSON, is returned as is unless the data
+// has since been modified. Otherwise the data is encoded with EncodeProof.
+func (a *AnchorProof) EncodeData() (string, error) {
+	if a.encoded != "" {
+		if received, err := DecodeProof(a.encoded); err == nil && sameProofData(a.Data, received) {
+			return a.encoded, nil
+		}
+	}
+	return EncodeProof(a.Data)
+}
+
+// Returns whether the decoded proof data are the same. The data are compared as JSON, as proofs
+// unmarshalled from JSON hold numbers as float64 and byte slices as base64 strings.
+func sameProofData(a, b map[string]interface{}) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(x, y)
+}
+
 func DecodeProof(data string) (map[string]interface{}, error) {
-	b, err := base64.StdEncoding.DecodeString(data)
+	b, err := inflateProof(data)
 	if err != nil {
 		return nil, err
 	}
+	var m map[string]interface{}
+	if err := msgpack.NewDecoder(bytes.NewReader(b)).UseJSONTag(true).Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
 
+// Decodes the base64 and zlib layers of the proof data, returning the msgpack encoded proof.
+func inflateProof(data string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
 	z, err := zlib.NewReader(bytes.NewReader(b))
 	if err != nil {
 		return nil, err
 	}
 	defer z.Close()
-	var m map[string]interface{}
-	if err := msgpack.NewDecoder(z).UseJSONTag(true).Decode(&m); err != nil {
+	return ioutil.ReadAll(z)
+}
+
+// The order of the keys of the proof formats' objects, as encoded by the anchor service. Keys
+// are only ever compared to keys of the same object, so a single order covers every object.
+var proofKeyOrder = map[string]int{}
+
+func init() {
+	keys := []string{
+		// CHP_PATH
+		"@context", "type", "hash", "hash_id_node", "hash_submitted_node_at", "hash_id_core",
+		"hash_submitted_core_at", "label", "ops", "branches", "l", "r", "op", "anchors", "anchor_id", "uris",
+		// ETH_TRIE
+		"anchorType", "txnId", "txnUri", "blockTime", "blockNumber", "trieNodes",
+	}
+	for i, k := range keys {
+		proofKeyOrder[k] = i
+	}
+}
+
+// EncodeProof encodes the decoded proof data into the wire format, performing the inverse of
+// DecodeProof (msgpack -> zlib -> base64). The keys are written in the order of the proof
+// format, followed by any unknown keys sorted, so the msgpack encoding of an unmodified proof
+// is identical to the anchor service's.
+//
+// Only the msgpack layer matches: the zlib stream, and so the returned string, generally differs
+// from the data received, although both decode to the same proof. Use AnchorProof.EncodeData to
+// get back the exact data received.
+func EncodeProof(data map[string]interface{}) (string, error) {
+	m, err := marshalProof(data)
+	if err != nil {
+		return "", err
+	}
+	return deflateProof(m)
+}
+
+// Encodes the msgpack encoded proof with the zlib and base64 layers.
+func deflateProof(m []byte) (string, error) {
+	var buf bytes.Buffer
+	z := zlib.NewWriter(&buf)
+	if _, err := z.Write(m); err != nil {
+		return "", err
+	}
+	if err := z.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Encodes the decoded proof data to msgpack.
+func marshalProof(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeProofValue(msgpack.NewEncoder(&buf).UseJSONTag(true), reflect.ValueOf(data)); err != nil {
 		return nil, err
 	}
-	return m, nil
+	return buf.Bytes(), nil
+}
+
+// Encodes the value, writing the keys of maps in the order of the proof formats.
+func encodeProofValue(e *msgpack.Encoder, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return e.EncodeNil()
+		}
+		return encodeProofValue(e, v.Elem())
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			return e.EncodeNil()
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := keys[i].String(), keys[j].String()
+			ai, aok := proofKeyOrder[a]
+			bi, bok := proofKeyOrder[b]
+			if aok != bok {
+				return aok
+			}
+			if aok && ai != bi {
+				return ai < bi
+			}
+			return a < b
+		})
+		if err := e.EncodeMapLen(len(keys)); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := e.EncodeString(k.String()); err != nil {
+				return err
+			}
+			if err := encodeProofValue(e, v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return e.EncodeNil()
+		}
+		if err := e.EncodeArrayLen(v.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeProofValue(e, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return e.Encode(v.Interface())
 }
@@ -1,7 +1,13 @@
 package anchor
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestDecodeCHP_PATH(t *testing.T) {
@@ -10,7 +16,9 @@ func TestDecodeCHP_PATH(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	_ = p.(map[string]interface{})
+	if len(p) == 0 {
+		t.FailNow()
+	}
 }
 
 func TestDecodeETH_TRIE(t *testing.T) {
@@ -20,7 +28,9 @@ func TestDecodeETH_TRIE(t *testing.T) {
 		t.FailNow()
 	}
 
-	_ = p.(map[string]interface{})
+	if len(p) == 0 {
+		t.FailNow()
+	}
 }
 
 func TestDecodeCHP_PATH_SIGNED(t *testing.T) {
@@ -29,7 +39,9 @@ func TestDecodeCHP_PATH_SIGNED(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	_ = p.(map[string]interface{})
+	if len(p) == 0 {
+		t.FailNow()
+	}
 }
 
 func TestDecodeETH_TRIE_SIGNED(t *testing.T) {
@@ -38,5 +50,251 @@ func TestDecodeETH_TRIE_SIGNED(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	_ = p.(map[string]interface{})
+	if len(p) == 0 {
+		t.FailNow()
+	}
+}
+
+func TestEncodeProof(t *testing.T) {
+	for _, data := range []string{chpPathData, chpPathSignedData, ethTrieData, ethTrieSignedData} {
+		exp, err := DecodeProof(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := EncodeProof(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		act, err := DecodeProof(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(exp, act) {
+			t.Fatal("decoded proof does not match the original")
+		}
+		// Only the msgpack layer matches the anchor service's encoding, the zlib stream and so
+		// the encoded string are not expected to.
+		received, err := inflateProof(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := inflateProof(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(received, m) {
+			t.Fatal("msgpack encoding does not match the original")
+		}
+	}
+}
+
+func TestEncodeProof_GoTypes(t *testing.T) {
+	// Proofs built in Go, e.g. by the merkle tree, are encoded as their decoded equivalent.
+	data := map[string]interface{}{
+		"hash": "ab",
+		"branches": []map[string]interface{}{{
+			"ops":   []map[string]string{{"r": "cd"}, {"op": "sha-256"}},
+			"label": "pdb_batch_branch",
+		}},
+	}
+	exp := map[string]interface{}{
+		"hash": "ab",
+		"branches": []interface{}{map[string]interface{}{
+			"label": "pdb_batch_branch",
+			"ops":   []interface{}{map[string]interface{}{"r": "cd"}, map[string]interface{}{"op": "sha-256"}},
+		}},
+	}
+	a, err := EncodeProof(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EncodeProof(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("expected Go types to be encoded as their decoded equivalent")
+	}
+}
+
+func TestAnchorProof_EncodeData(t *testing.T) {
+	for _, data := range []string{chpPathData, chpPathSignedData, ethTrieData, ethTrieSignedData} {
+		p := &AnchorProof{}
+		if err := p.FromProof(&Proof{Hash: "ab", BatchId: "1", Data: data}); err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := p.EncodeData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded != data {
+			t.Fatal("expected unmodified data to be encoded as received")
+		}
+		p.Data["hash"] = "cd"
+		if encoded, err = p.EncodeData(); err != nil {
+			t.Fatal(err)
+		}
+		m, err := DecodeProof(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["hash"] != "cd" {
+			t.Fatal("expected modified data to be encoded")
+		}
+	}
+}
+
+func TestAnchorProof_EncodeData_JSON(t *testing.T) {
+	for _, data := range []string{chpPathData, chpPathSignedData, ethTrieData, ethTrieSignedData} {
+		p := &AnchorProof{}
+		if err := p.FromProof(&Proof{Hash: "ab", BatchId: "1", Data: data}); err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imported := &AnchorProof{}
+		if err := json.Unmarshal(b, imported); err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := imported.EncodeData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded != data {
+			t.Fatal("expected the data received to be kept through JSON")
+		}
+		imported.Data["hash"] = "cd"
+		if encoded, err = imported.EncodeData(); err != nil {
+			t.Fatal(err)
+		}
+		if encoded == data {
+			t.Fatal("expected modified data to be encoded")
+		}
+	}
+}
+
+func TestAnchorProof_FromProof(t *testing.T) {
+	created := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	p := &AnchorProof{}
+	err := p.FromProof(&Proof{
+		Hash:        "ab",
+		BatchId:     "1",
+		AnchorType:  Anchor_HEDERA,
+		BatchStatus: Batch_PENDING,
+		Format:      Proof_CHP_PATH,
+		Batch: &Batch{
+			Id:        "1",
+			Status:    Batch_PENDING,
+			Size:      2,
+			CreatedAt: timestamppb.New(created),
+			Data:      `{"txnId":"0x1"}`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AnchorType != Anchor_HEDERA || p.Status != Batch_PENDING || p.Format != Proof_CHP_PATH {
+		t.Fatalf("unexpected proof %+v", p)
+	}
+	m := p.Metadata
+	if m.Id != "1" || m.Status != Batch_PENDING || m.Size != 2 || !m.CreatedAt.Equal(created) || !m.FlushedAt.IsZero() {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+	if m.Data["txnId"] != "0x1" {
+		t.Fatalf("unexpected batch data %v", m.Data)
+	}
+}
+
+func TestAnchorProof_JSON(t *testing.T) {
+	exp := &AnchorProof{
+		Id:         "ab:1",
+		AnchorType: Anchor_HEDERA,
+		BatchId:    "1",
+		Status:     Batch_CONFIRMED,
+		Format:     Proof_CHP_PATH_SIGNED,
+		Hash:       "ab",
+		Metadata: &BatchMetadata{
+			Id:          "1",
+			Status:      Batch_CONFIRMED,
+			Size:        2,
+			CreatedAt:   time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			FinalizedAt: time.Date(2021, 3, 1, 0, 1, 0, 0, time.UTC),
+			Data:        map[string]interface{}{"txnId": "0x1"},
+		},
+		Data: map[string]interface{}{"hash": "ab"},
+	}
+	b, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["AnchorType"] != "HEDERA" || raw["Status"] != "CONFIRMED" || raw["Format"] != "CHP_PATH_SIGNED" {
+		t.Fatalf("expected enums as strings, got %s", b)
+	}
+	act := &AnchorProof{}
+	if err := json.Unmarshal(b, act); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected %+v, got %+v", exp, act)
+	}
+}
+
+func TestAnchorProof_UnmarshalJSON_Untyped(t *testing.T) {
+	// The form of proofs marshalled before the fields were typed.
+	b := []byte(`{"Id":"ab:1","AnchorType":"ETH","BatchId":"1","Status":"PENDING","Format":"CHP_PATH","Hash":"ab","Metadata":{"txnId":"0x1"},"Data":{"hash":"ab"}}`)
+	p := &AnchorProof{}
+	if err := json.Unmarshal(b, p); err != nil {
+		t.Fatal(err)
+	}
+	if p.AnchorType != Anchor_ETH || p.Status != Batch_PENDING || p.Format != Proof_CHP_PATH {
+		t.Fatalf("unexpected proof %+v", p)
+	}
+	if p.Metadata == nil || p.Metadata.Data["txnId"] != "0x1" {
+		t.Fatalf("unexpected metadata %+v", p.Metadata)
+	}
+	if err := json.Unmarshal([]byte(`{"Format":"UNKNOWN"}`), p); err == nil {
+		t.Fatal("expected unknown format to fail")
+	}
+}
+
+func TestAnchorProof_MarshalJSON_Value(t *testing.T) {
+	p := AnchorProof{Id: "ab:1", AnchorType: Anchor_HEDERA, BatchId: "1", Status: Batch_CONFIRMED, Hash: "ab"}
+	exp, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(exp, b) {
+		t.Fatalf("expected a proof value to be marshalled as a pointer, got %s", b)
+	}
+	b, err = json.Marshal([]AnchorProof{p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "["+string(exp)+"]" {
+		t.Fatalf("expected a slice of proofs to be marshalled as pointers, got %s", b)
+	}
+}
+
+func TestAnchorProof_UnmarshalJSON_UntypedMetadata(t *testing.T) {
+	// Proofs stored before the fields were typed may hold batch data which is not an object.
+	for _, metadata := range []string{`"0x1"`, `["0x1"]`, `null`} {
+		b := []byte(`{"Id":"ab:1","AnchorType":"ETH","BatchId":"1","Status":"PENDING","Format":"CHP_PATH","Hash":"ab","Metadata":` + metadata + `,"Data":{"hash":"ab"}}`)
+		p := &AnchorProof{}
+		if err := json.Unmarshal(b, p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Metadata != nil || p.Hash != "ab" {
+			t.Fatalf("unexpected proof %+v", p)
+		}
+	}
 }
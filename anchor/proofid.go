@@ -0,0 +1,87 @@
+package anchor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProofID identifies a proof by its hash and batch ID, and optionally its anchor type, in the
+// form 'hash:batchId' or 'hash:batchId:ANCHOR_TYPE'. An ID with the anchor type fully identifies
+// a proof.
+//
+// As Anchor_ETH is the zero anchor type, AnchorType is only used if HasAnchorType is set.
+type ProofID struct {
+	Hash          string      // the proof's hash
+	BatchId       string      // the proof's batch ID
+	AnchorType    Anchor_Type // the proof's anchor type
+	HasAnchorType bool        // whether the anchor type is known
+}
+
+// ParseProofID parses and validates the proof ID.
+func ParseProofID(id string) (ProofID, error) {
+	s := strings.Split(id, ":")
+	if len(s) != 2 && len(s) != 3 {
+		return ProofID{}, fmt.Errorf("%w '%s': expected 'hash:batchId[:anchorType]'", ErrInvalidProofID, id)
+	}
+	p := ProofID{Hash: s[0], BatchId: s[1]}
+	if len(s) == 3 {
+		at, err := getAnchorType(s[2])
+		if err != nil {
+			return ProofID{}, fmt.Errorf("%w: %s", ErrInvalidProofID, err.Error())
+		}
+		p.AnchorType, p.HasAnchorType = at, true
+	}
+	if err := p.Validate(); err != nil {
+		return ProofID{}, err
+	}
+	return p, nil
+}
+
+// String returns the ID in the form 'hash:batchId', followed by ':ANCHOR_TYPE' if known.
+func (p ProofID) String() string {
+	id := generateProofId(p.Hash, p.BatchId)
+	if p.HasAnchorType {
+		id += ":" + p.AnchorType.String()
+	}
+	return id
+}
+
+// Validate checks the hash is lowercase hex, the batch ID is set, and the anchor type, if set,
+// is known.
+func (p ProofID) Validate() error {
+	if err := validateHash(p.Hash, 0); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidProofID, err.Error())
+	}
+	if p.BatchId == "" || strings.Contains(p.BatchId, ":") {
+		return fmt.Errorf("%w: invalid batch ID '%s'", ErrInvalidProofID, p.BatchId)
+	}
+	if p.HasAnchorType {
+		if _, ok := Anchor_Type_name[int32(p.AnchorType)]; !ok {
+			return fmt.Errorf("%w: %s '%d'", ErrInvalidProofID, ErrInvalidAnchorType.Error(), p.AnchorType)
+		}
+	}
+	return nil
+}
+
+// Returns the anchor type of the ID, which must match the given anchor type if it is not nil.
+func (p ProofID) anchorType(anchorType interface{}) (Anchor_Type, error) {
+	if anchorType == nil {
+		if !p.HasAnchorType {
+			return Anchor_ETH, fmt.Errorf("%w: proof ID '%s' has no anchor type", ErrInvalidAnchorType, p)
+		}
+		return p.AnchorType, nil
+	}
+	at, err := getAnchorType(anchorType)
+	if err != nil {
+		return at, err
+	}
+	if p.HasAnchorType && p.AnchorType != at {
+		return at, fmt.Errorf("%w: proof ID '%s' is not of anchor type '%s'", ErrInvalidAnchorType, p, at)
+	}
+	return at, nil
+}
+
+// ProofID returns the ID of the proof including its anchor type.
+func (a *AnchorProof) ProofID() ProofID {
+	return ProofID{Hash: a.Hash, BatchId: a.BatchId, AnchorType: a.AnchorType, HasAnchorType: true}
+}
@@ -0,0 +1,87 @@
+package anchor
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseProofID(t *testing.T) {
+	tests := []struct {
+		id    string
+		want  ProofID
+		valid bool
+	}{
+		{"ab:1", ProofID{Hash: "ab", BatchId: "1"}, true},
+		{"ab:1:ETH", ProofID{Hash: "ab", BatchId: "1", AnchorType: Anchor_ETH, HasAnchorType: true}, true},
+		{"ab", ProofID{}, false},
+		{"ab:", ProofID{}, false},
+		{":1", ProofID{}, false},
+		{"AB:1", ProofID{}, false},
+		{"ab:1:UNKNOWN", ProofID{}, false},
+		{"ab:1:ETH:x", ProofID{}, false},
+		{"ab:1:HEDERA_MAINNET", ProofID{Hash: "ab", BatchId: "1", AnchorType: Anchor_HEDERA_MAINNET, HasAnchorType: true}, true},
+		{"", ProofID{}, false},
+	}
+	for _, test := range tests {
+		p, err := ParseProofID(test.id)
+		if !test.valid {
+			if !errors.Is(err, ErrInvalidProofID) {
+				t.Fatalf("expected '%s' to be invalid, got %v", test.id, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected '%s' to be valid: %s", test.id, err.Error())
+		}
+		if p != test.want {
+			t.Fatalf("expected %+v, got %+v", test.want, p)
+		}
+		if p.String() != test.id {
+			t.Fatalf("expected '%s', got '%s'", test.id, p.String())
+		}
+	}
+}
+
+func TestProofID_AnchorType(t *testing.T) {
+	p := ProofID{Hash: "ab", BatchId: "1", AnchorType: Anchor_HEDERA, HasAnchorType: true}
+	if at, err := p.anchorType(nil); err != nil || at != Anchor_HEDERA {
+		t.Fatalf("expected HEDERA, got %v %v", at, err)
+	}
+	if at, err := p.anchorType("HEDERA"); err != nil || at != Anchor_HEDERA {
+		t.Fatalf("expected HEDERA, got %v %v", at, err)
+	}
+	if _, err := p.anchorType(Anchor_ETH); !errors.Is(err, ErrInvalidAnchorType) {
+		t.Fatalf("expected mismatched anchor type error, got %v", err)
+	}
+	if _, err := (ProofID{Hash: "ab", BatchId: "1"}).anchorType(nil); !errors.Is(err, ErrInvalidAnchorType) {
+		t.Fatalf("expected missing anchor type error, got %v", err)
+	}
+}
+
+func TestProofID_Validate(t *testing.T) {
+	if err := (ProofID{Hash: "ab", BatchId: "1", AnchorType: Anchor_Type(100), HasAnchorType: true}).Validate(); !errors.Is(err, ErrInvalidProofID) {
+		t.Fatalf("expected unknown anchor type error, got %v", err)
+	}
+	if err := (ProofID{Hash: "ab", BatchId: "1", AnchorType: Anchor_Type(100)}).Validate(); err != nil {
+		t.Fatalf("expected the anchor type to be ignored, got %v", err)
+	}
+}
+
+func TestAnchorProof_ProofID(t *testing.T) {
+	p := &AnchorProof{Id: "ab:1", Hash: "ab", BatchId: "1", AnchorType: Anchor_ETH}
+	if p.ProofID().String() != "ab:1:ETH" {
+		t.Fatalf("unexpected proof ID '%s'", p.ProofID())
+	}
+}
+
+func TestClient_GetProofByID_NoAnchorType(t *testing.T) {
+	// The fake does not implement GetProof, so any RPC would panic.
+	client := &Client{anchor: &fakeAnchorClient{}}
+	if _, err := client.GetProofByID(context.Background(), ProofID{Hash: "ab", BatchId: "1"}); !errors.Is(err, ErrInvalidAnchorType) {
+		t.Fatalf("expected missing anchor type error, got %v", err)
+	}
+	if _, err := client.GetProof(context.Background(), "ab:1", nil); !errors.Is(err, ErrInvalidAnchorType) {
+		t.Fatalf("expected missing anchor type error, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package anchor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The methods which are not idempotent, so a retry may repeat a call the service already processed.
+var nonIdempotentMethods = map[string]bool{
+	"/anchor.AnchorService/SubmitProof": true,
+}
+
+// RetryPolicy configures the retrying of failed unary calls with jittered exponential backoff.
+// Calls which are not idempotent (SubmitProof) are not retried unless RetrySubmitProof is set, as
+// no status code guarantees the service did not process the failed call, e.g. Unavailable is also
+// returned when the connection drops after the service received the call.
+type RetryPolicy struct {
+	// The maximum attempts of each call, including the first. Defaults to 4.
+	MaxAttempts int
+	// The delay before the first retry, doubled on each retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// The maximum delay between retries. Defaults to 5s.
+	MaxBackoff time.Duration
+	// The status codes which are retried. Defaults to Unavailable, DeadlineExceeded and
+	// ResourceExhausted.
+	Codes []codes.Code
+	// Whether SubmitProof calls are retried. A retried submission may submit the hash twice.
+	RetrySubmitProof bool
+}
+
+// RetryError is returned by calls made with a retry policy, recording the attempts made before
+// the call failed.
+type RetryError struct {
+	Attempts int   // the attempts made
+	Err      error // the error of the last attempt
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s (after %d attempts)", e.Err.Error(), e.Attempts)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns the status of the last attempt so status.Code works on the error.
+func (e *RetryError) GRPCStatus() *status.Status {
+	return status.Convert(e.Err)
+}
+
+// Returns a copy of the policy with the defaults applied.
+func (p RetryPolicy) withDefaults() *RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 4
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 5 * time.Second
+	}
+	if len(p.Codes) == 0 {
+		p.Codes = []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted}
+	}
+	return &p
+}
+
+// Returns whether the failed call to the method can be retried.
+func (p *RetryPolicy) retryable(method string, err error) bool {
+	if nonIdempotentMethods[method] && !p.RetrySubmitProof {
+		return false
+	}
+	code := status.Code(err)
+	for _, c := range p.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the interceptor retrying unary calls according to the policy.
+func (p *RetryPolicy) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		b := &backoff{min: p.InitialBackoff, max: p.MaxBackoff}
+		attempts := 0
+		for {
+			attempts++
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil {
+				return nil
+			}
+			// A done context means the caller gave up, so the error is not retried.
+			if attempts >= p.MaxAttempts || ctx.Err() != nil || !p.retryable(method, err) {
+				return &RetryError{Attempts: attempts, Err: err}
+			}
+			if sleep(ctx, b.next()) != nil {
+				return &RetryError{Attempts: attempts, Err: err}
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package anchor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Returns an invoker failing with the errors in order before succeeding, counting the calls.
+func failingInvoker(calls *int, errs ...error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestRetryPolicy_Retries(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Millisecond}.withDefaults()
+	calls := 0
+	invoker := failingInvoker(&calls, status.Error(codes.Unavailable, ""), status.Error(codes.DeadlineExceeded, ""))
+	err := policy.unaryInterceptor()(context.Background(), "/anchor.AnchorService/GetProof", nil, nil, nil, invoker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryPolicy_Exhausted(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}.withDefaults()
+	calls := 0
+	unavailable := status.Error(codes.Unavailable, "")
+	invoker := failingInvoker(&calls, unavailable, unavailable, unavailable)
+	err := policy.unaryInterceptor()(context.Background(), "/anchor.AnchorService/GetBatch", nil, nil, nil, invoker)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 2 {
+		t.Fatalf("expected a retry error after 2 attempts, got %v", err)
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Fatal("expected the status of the last attempt")
+	}
+}
+
+func TestRetryPolicy_NotRetryable(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Millisecond}.withDefaults()
+	calls := 0
+	invoker := failingInvoker(&calls, status.Error(codes.InvalidArgument, ""))
+	err := policy.unaryInterceptor()(context.Background(), "/anchor.AnchorService/GetProof", nil, nil, nil, invoker)
+	if err == nil || calls != 1 {
+		t.Fatal("invalid argument should not be retried")
+	}
+}
+
+func TestRetryPolicy_SubmitProof(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Millisecond}.withDefaults()
+
+	// The proof may have been submitted, so no failure is retried by default.
+	for _, code := range []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted} {
+		calls := 0
+		invoker := failingInvoker(&calls, status.Error(code, ""))
+		if err := policy.unaryInterceptor()(context.Background(), "/anchor.AnchorService/SubmitProof", nil, nil, nil, invoker); err == nil || calls != 1 {
+			t.Fatalf("%s should not be retried for SubmitProof", code)
+		}
+	}
+
+	policy = RetryPolicy{InitialBackoff: time.Millisecond, RetrySubmitProof: true}.withDefaults()
+	calls := 0
+	invoker := failingInvoker(&calls, status.Error(codes.Unavailable, ""))
+	if err := policy.unaryInterceptor()(context.Background(), "/anchor.AnchorService/SubmitProof", nil, nil, nil, invoker); err != nil || calls != 2 {
+		t.Fatal("unavailable should be retried for SubmitProof when enabled")
+	}
+}
+
+func TestRetryPolicy_ContextDone(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Hour}.withDefaults()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	calls := 0
+	invoker := failingInvoker(&calls, status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""))
+	err := policy.unaryInterceptor()(ctx, "/anchor.AnchorService/GetProof", nil, nil, nil, invoker)
+	if err == nil || calls != 1 {
+		t.Fatal("expected the backoff to stop once the context is done")
+	}
+}
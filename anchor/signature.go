@@ -0,0 +1,313 @@
+package anchor
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The prefixes of the signature and signing certificate values within signed proofs.
+const (
+	signaturePrefix   = "sig:"
+	certificatePrefix = "cert:"
+)
+
+// KeySet is a set of public keys and certificates trusted to sign proofs.
+type KeySet struct {
+	keys  map[string]crypto.PublicKey
+	roots *x509.CertPool
+}
+
+// NewKeySet creates a new empty key set.
+func NewKeySet() *KeySet {
+	return &KeySet{
+		keys:  make(map[string]crypto.PublicKey),
+		roots: x509.NewCertPool(),
+	}
+}
+
+// AddKey adds a trusted public key with the given ID. The service signs proofs with RSA keys, so
+// other keys never verify a signature.
+func (k *KeySet) AddKey(id string, key crypto.PublicKey) *KeySet {
+	k.keys[id] = key
+	return k
+}
+
+// AddCertificate adds a trusted certificate, using the certificate's subject as the key ID. Signing
+// certificates carried by proofs are trusted if they are, or are issued by, a trusted certificate.
+func (k *KeySet) AddCertificate(cert *x509.Certificate) *KeySet {
+	k.roots.AddCert(cert)
+	return k.AddKey(cert.Subject.String(), cert.PublicKey)
+}
+
+// AddPEM adds every public key and certificate found in the PEM encoded data.
+func (k *KeySet) AddPEM(id string, data []byte) error {
+	found := false
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return err
+			}
+			k.AddCertificate(cert)
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return err
+			}
+			k.AddKey(id, key)
+		case "RSA PUBLIC KEY":
+			key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return err
+			}
+			k.AddKey(id, key)
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return errors.New("no public keys or certificates found in PEM data")
+	}
+	return nil
+}
+
+// Returns the ID of the trusted key equal to the given key, or an empty string.
+func (k *KeySet) keyId(key crypto.PublicKey) string {
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return ""
+	}
+	for _, id := range k.ids() {
+		if pub.Equal(k.keys[id]) {
+			return id
+		}
+	}
+	return ""
+}
+
+// Returns the IDs of the keys in order, so results are deterministic.
+func (k *KeySet) ids() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SignatureVerification is the result of verifying the signature of a signed proof.
+type SignatureVerification struct {
+	Verified    bool              // whether the signature was made by a trusted key
+	KeyId       string            // the ID of the trusted key or certificate that made the signature
+	Signed      string            // hex encoded value covered by the signature
+	Signature   []byte            // the signature extracted from the proof
+	Certificate *x509.Certificate // the signing certificate extracted from the proof, if any
+}
+
+// VerifySignature extracts the signature, and the signing certificate if the proof carries one, from
+// a CHP_PATH_SIGNED or ETH_TRIE_SIGNED proof and checks it was made by one of the trusted keys.
+//
+// The service signs the value preceding the signature (the proof hash for CHP paths, the root of the
+// data trie for ETH tries) using RSASSA-PKCS1-v1_5 with SHA-256. When the proof carries the signing
+// certificate, the signature is checked against the certificate's key, and the certificate must be
+// trusted. A proof whose signature does not match any trusted key is reported as unverified rather
+// than as an error.
+func VerifySignature(proof *AnchorProof, keys *KeySet) (*SignatureVerification, error) {
+	var s *proofSignature
+	var err error
+	switch proof.Format {
+	case Proof_CHP_PATH_SIGNED:
+		s, err = extractCHPSignature(proof.Data)
+	case Proof_ETH_TRIE_SIGNED:
+		s, err = extractETHTrieSignature(proof.Data, proof.Hash)
+	default:
+		return nil, fmt.Errorf("%w '%s': proof is not signed", ErrUnsupportedFormat, proof.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := &SignatureVerification{
+		Signed:    hex.EncodeToString(s.signed),
+		Signature: s.sig,
+	}
+	if s.cert != nil {
+		if res.Certificate, err = x509.ParseCertificate(s.cert); err != nil {
+			return nil, fmt.Errorf("invalid signing certificate: %s", err.Error())
+		}
+	}
+	if keys == nil {
+		return res, nil
+	}
+	if res.Certificate != nil {
+		if !verifySignature(res.Certificate.PublicKey, s.signed, s.sig) {
+			return res, nil
+		}
+		if id := keys.keyId(res.Certificate.PublicKey); id != "" {
+			res.Verified, res.KeyId = true, id
+		} else if _, err := res.Certificate.Verify(x509.VerifyOptions{
+			Roots:     keys.roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}); err == nil {
+			res.Verified, res.KeyId = true, res.Certificate.Subject.String()
+		}
+		return res, nil
+	}
+	for _, id := range keys.ids() {
+		if verifySignature(keys.keys[id], s.signed, s.sig) {
+			res.Verified, res.KeyId = true, id
+			break
+		}
+	}
+	return res, nil
+}
+
+// Checks the RSASSA-PKCS1-v1_5 SHA-256 signature of the message against the public key.
+func verifySignature(key crypto.PublicKey, message []byte, sig []byte) bool {
+	k, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return false
+	}
+	digest := sha256.Sum256(message)
+	return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+}
+
+// proofSignature is the signature extracted from a signed proof.
+type proofSignature struct {
+	signed []byte // the value covered by the signature
+	sig    []byte // the signature
+	cert   []byte // the DER encoded signing certificate, if the proof carries one
+}
+
+// Extracts the signature from the CHP path along with the value it signs, which is the value
+// computed immediately before the signature is appended. A signing certificate is carried by a
+// later op of the same branch.
+func extractCHPSignature(data map[string]interface{}) (*proofSignature, error) {
+	path, err := ToCHPPath(data)
+	if err != nil {
+		return nil, err
+	}
+	value, err := hex.DecodeString(path.Hash)
+	if err != nil || len(value) == 0 {
+		return nil, fmt.Errorf("proof hash '%s' is not hex", path.Hash)
+	}
+	s, err := findCHPSignature(value, path.Branches)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, errors.New("no signature found in proof")
+	}
+	return s, nil
+}
+
+// Walks the branches depth first until the first signature op is found.
+func findCHPSignature(value []byte, branches []*CHPBranch) (*proofSignature, error) {
+	for _, branch := range branches {
+		if branch == nil {
+			return nil, errors.New("proof branch must be an object")
+		}
+		current := append([]byte{}, value...)
+		for i, op := range branch.Ops {
+			if sig, ok, err := chpPrefixedValue(op, signaturePrefix, branch.Label); err != nil {
+				return nil, err
+			} else if ok {
+				s := &proofSignature{signed: current, sig: sig}
+				for _, next := range branch.Ops[i+1:] {
+					if cert, ok, err := chpPrefixedValue(next, certificatePrefix, branch.Label); err != nil {
+						return nil, err
+					} else if ok {
+						s.cert = cert
+						break
+					}
+				}
+				return s, nil
+			}
+			next, err := evaluateCHPOp(current, op, branch.Label, &OfflineVerification{})
+			if err != nil {
+				return nil, err
+			}
+			current = next
+		}
+		s, err := findCHPSignature(current, branch.Branches)
+		if err != nil || s != nil {
+			return s, err
+		}
+	}
+	return nil, nil
+}
+
+// Returns the base64 decoded l/r value of the op if it has the given prefix.
+func chpPrefixedValue(op *CHPOp, prefix string, label string) ([]byte, bool, error) {
+	if op == nil {
+		return nil, false, nil
+	}
+	for _, s := range []string{op.L, op.R} {
+		if strings.HasPrefix(s, prefix) {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid '%s' value in branch '%s'", prefix, label)
+			}
+			return b, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// Extracts the signature from the ETH trie. Signed tries hold the signature, and optionally the
+// signing certificate, alongside the root of the data trie, which is the value being signed.
+func extractETHTrieSignature(data map[string]interface{}, hash string) (*proofSignature, error) {
+	trie, err := toETHTrie(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("proof hash '%s' is not hex", hash)
+	}
+	nodes, root, err := trie.nodes()
+	if err != nil {
+		return nil, err
+	}
+	roots, err := searchETHTrie(root, key, nodes, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) < 2 {
+		return nil, errors.New("no signature found in proof")
+	}
+	signed, _ := hex.DecodeString(roots[len(roots)-1])
+	leaves := make([]*ethTrieLeaf, 0)
+	if err := collectETHTrieLeaves(nodes[roots[len(roots)-2]], []byte{}, nodes, &leaves); err != nil {
+		return nil, err
+	}
+	s := &proofSignature{signed: signed}
+	for _, l := range leaves {
+		switch {
+		case bytes.Equal(l.key, []byte(signaturePrefix)):
+			s.sig = l.value
+		case bytes.Equal(l.key, []byte(certificatePrefix)):
+			s.cert = l.value
+		}
+	}
+	if s.sig == nil {
+		return nil, errors.New("no signature found in proof")
+	}
+	return s, nil
+}
@@ -0,0 +1,245 @@
+package anchor
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+)
+
+const (
+	// The public key of the test signing key.
+	testSigningKey = `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAsExfGE9HQFRHExv8SM8I
+QwvGoqCi/sjBhjRrS5vpiXD0ZDsohyh40RsGktoK4e4lSQ/BdwhnM6zpLX6DKzbR
+0VEqylMp9szEFEjm07bGtAcdtublfvV4uLvFk//VzoFA1yGKtYItuKjIhJv3bt55
+S2tR2WvFBi0cGhOl7kgxsd2X7j2mnHOhpQOtxa2/G+PoM+Aan+hOUJhwGasOuxSP
+B+8phi3E48z/XnBGbsRwv4rVMSvu8HNJahw4Lz4HBnUeijO/IQjhScLnTWnTMkBH
+I/j2bIdiAzlrI0oVQkQsEtwVDxHPLrNdWgzENC2oMLr667q2vFHdDxXVwx00pUW3
+eQIDAQAB
+-----END PUBLIC KEY-----`
+	// A CHP_PATH_SIGNED proof of chpPathHash signed by the test signing key.
+	testSignedCHPPathData = "eJyckstO20wcR/W9y7eLUtszHl8iIRHi3PGFxCQQVYpmxuNLYnt8I3Gyg1W3qE9AoYJWXbZd9j3o01QBiV033Z6/zln89fvwdEx5WrG6+hVWVVa2BGELI+8dLwKBhjhKMx6llbCBD9UuY187b+ghxGX4fAyoD2SPYOQTEUORAYVpkABMEdEZ0JlEJEViiEo+VmWAmApFGUIF6FAjugbkb4fMMvKWKffY8/8eFgGkiDappqOmJDG9iSHwmwADzIBHKGXyzxelvCJJVFXs1Vzi6jsQgdQUYRMgF8AWklpQX7zlKS/+MX8w/5Z/IgVOacjK25vPMSYs/pF5ZMmqcHnAvFi+3u95Vn68vit+//e+jIKWqWUTU+vNxNhWiNS/HLbzk1EuCQFTjXbYHs/8qs2ddTQMV2Gwq3dRScuuC1w7TC42ziRbn4KgniiZK4nDHsPyWsVy2+5MkWAl2n4AFm7D00JBOQu2Fyge1zzvi2ZvHli9VErkPPc7Xbut1NQ6L/bzhUGRqSmWPXTQ3EFbmyYLYzL1AFtZW7MG7mwg9jzp1CXiJfH93FQCcbbfMmeahIYC+/2+n3Wck818oJ3YgwWfzCfzXZQS0yLnlG9Yw107SJcvc6MhSIa/W7k7Qamj/VVtjUUrmVabtRyPkjZRIeqqW9fmoyRe1Gq5YlZkUGPhYJPEuVqOTwe+2SirDZt7g1xt5JMrl3RXmeiti9E2OTs6uv7Es8cyxE2AlOvHw+N5Ud7evCz3nuL4C05pyItl5N1JfwYA6LUH+w=="
+	// The certificate of the test CA.
+	testSigningCA = `-----BEGIN CERTIFICATE-----
+MIIC8TCCAdmgAwIBAgIBATANBgkqhkiG9w0BAQsFADAZMRcwFQYDVQQDEw5BbmNo
+b3IgVGVzdCBDQTAgFw0yMTAxMDEwMDAwMDBaGA85OTk5MTIzMTAwMDAwMFowGTEX
+MBUGA1UEAxMOQW5jaG9yIFRlc3QgQ0EwggEiMA0GCSqGSIb3DQEBAQUAA4IBDwAw
+ggEKAoIBAQDRagdddvPR45FHMZbAJMN1YvefsO+QL/VVAsrDEWZsGuPVgj0G9m1f
+dRjJ+l9l1e0qfLIom4UfJw8aeNUhtRDBCe5JYmXWHAsVGUQgaD2Mx5MFgye2zKUn
+N8ief8TZgknlBXXRnzr8fqZ6fQNk03nSF694u7z4wENkyY6kJQA4p353SJNmIcJB
+b2TTWmB/lrM8tvscNxsmlSw/tcodRKKy6uindOwvulB/FuCaAykS7DiWbm0lW5GN
+pzeNW1BoDi9pC6kzSEe2rq/dj7qzjk5SDaDP/EpIopHzO/3rrWd5J2QIbCv4lUin
+MNpAEYbX1fMT0YSBAaP34LkJjrXAdGqhAgMBAAGjQjBAMA4GA1UdDwEB/wQEAwIC
+BDAPBgNVHRMBAf8EBTADAQH/MB0GA1UdDgQWBBQi0WrIAmDxvYhFV2pXKeL0Sn/F
+YDANBgkqhkiG9w0BAQsFAAOCAQEAc+DhVMURNVf/9lNckRjvXyloYajQ+lgy/5iD
+CsVAPj4rfcuGjkWvJvgX2VzmmFJ17NHlJBzmNP8QbaYYjt5J3YF5P6GY3pCkfKMz
+T/RG3kLzP6DpKWx9AfeNH3RpeV2OU29vph+N4p2FbCt3kbD3451oi6x3smfNP8J2
+OmJvR9TMFeznnu3dywt2XCSO7lcPlAe+sb5hr/3dETEbUkRkUUC287Yry72F/ZaB
+SDJXMYk17vl9KQ3IW/FYomv7403/Q0/EVJfaeFarkPz5MlmJNYY4payVTiHOFMSR
+R8IO+BreOAENBXPwRhruiNDu7RAzcCGCXnFuJzIVgmUPYsLxag==
+-----END CERTIFICATE-----`
+	// A CHP_PATH_SIGNED proof of chpPathHash carrying the signing certificate issued by the test CA.
+	testCertSignedCHPPathData = "eJyclc2us8Ydh1X1VrqzToHBYDjSK2WGL4ONbT7MlyodAYMHsM23wbB7s+o2Um/gbVIlrbpsu+x9JHfQu6hOjpRN1U22z0/Po1nN/48/fJXW1ZA9h3/nw9D0rxQ1sQX+fd0RKs3jomrqohqokf1umJvsb9Iv6Ls87vMfvwLpBaxxEnOXhI5ZOgN8JrAJiFMuETMgZkzC8EzGpcwl3qwBl21Yes2yPBBZIREFsP77e+atwG9VjbMff4djGrApl76kgsi9MEwmvsQsuLyAGMQZwEmaZut//az0j+ReDEP2Yb7Fwz8ADZgXmn0BnAvYV455ZcXol3xad78y/27+v/wPSRdXaZ7133z9l1ucZLd/Njh5y4b87R3X3dvH/m3d9H/6/KX76Td/6Avy2m382TlyfM8y7OS2nq8EWtItt2kXGEF0HPRCyGKSH/HIzIa+2WJrf5BxT7LA8fCF0kS05814b548MbYrrCnNNumr8zOXFLoD9brcNYF7ajdcv3OPd3vfgjjYn9DeXNdqmMcCRqbHmVJpU06l08vj2ZuqnvNLg9QcbbpNDurWnmLVgR0Yq1xUxPJYPU6bbuGQVJO74o50Y4Nk3PBDv1lrbOXvsmDd96B72HtkHNWN4nhN6Z/nwSV2P8a9dzrLS4FD4DuHawGsR2iQHVypo3TS7Vodqau2o2mlD11fJzN1l6sW75Iz5AwfVa7b47pcLTI2xwd72Q48E5RXwTEaz4E3x7WN20LIp0+fv3Q//fY/adYNr6auS1wpSTDlCZx0BImOYAkPiFzb/Fpo4kQjaPUqlGFk2umkWqHsWZasTBxK7oc6YXXiad6CJSRbLiTqRM+mC5+mrEymDCdTRrEGBe7oXjnT1RfThR9craetqyQmumqQOSvwaTqWz5WxJs66at9S1iJncKuSuzebuo70/3mTokJ4lKAlwPddIjsJWgp8nsN0VN1DO2UFiNVWOzIBjmKaF2ZvW6zJSDYmVprerwXHkI2Ea7JqZI9V6aF92Ar79GrziB5EGV/tkQ/SVAjPgtKz7HN3itw5Uq0pOVwcDBjngq4GdVej2muxal2byy3izxys91m06bnxyFaGl7TNc5seHhg/Q4NKWEz0RaiBJcxYNnbZTsWYgvO5CapiM4vN/jhfTvsHr96kbjQQYssdzNKp0o4GsZ0TIXz7SI7Man6inqRsLgwXOB1clcU3Lzg8puS42q39m7RC5ck7+OoiDMziixOjBGirZttb1KpxFaonXnnonSeWkUlpHis8xlBaG6nCnnRXNDn5XjqpmWf+JEv2yjxbugwtiOrFnEwXHhE5eFtLQPAiKAiaElrDaTuFsmfTJUJkUmt41ge1naFBhATotodbb64KUWmopw8n2QqNXR3p+ZgeoKXskQVlQhQEYeYc6JWf7tADtMvxxIWRx/ckWmcW8Frt1BCGKYnLeXXEj5nayczgqNdDYfeeJ8bxFi1KqvEoUshJoDVhNWz04Uw/nQTgY1nORQSaqRW34conFBt4wXI1C3lyVlpxGVDD8g3Ob9I2d6VQgCqr7q6d6QAYPTde2JElR2VFBYqj0xOFiDw5kV+LUwVRzUzqsJhk5IWwpJgGb64OXAnb85Wd6cc67O+8mmunu9gedJ/VH8v5DK/aajizWcAamFeiPgREGa1xl9+v5vO8rJn5MfSq9zAiAlV/aHL30fGpWHuukyTiM3Cx1FbrPRWflLWeHNg+4sDM1FQIQtFWlpQKiqDXTJyZIPz0+c91832fxy+A4z9///Hh9t98/fOh+jaNb3+NqzSvu7cCf2H+OwDwfVW4"
+)
+
+// Creates a CHP_PATH_SIGNED proof of the hash signed by the key.
+func signedCHPProof(t *testing.T, key *rsa.PrivateKey, hash string) *AnchorProof {
+	h, _ := hex.DecodeString(hash)
+	digest := sha256.Sum256(h)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AnchorProof{
+		Format: Proof_CHP_PATH_SIGNED,
+		Hash:   hash,
+		Data: map[string]interface{}{
+			"hash": hash,
+			"branches": []interface{}{
+				map[string]interface{}{
+					"label": "pdb_eth_anchor_branch",
+					"ops": []interface{}{
+						map[string]interface{}{"r": signaturePrefix + base64.StdEncoding.EncodeToString(sig)},
+						map[string]interface{}{"op": "sha-256"},
+						map[string]interface{}{
+							"anchors": []interface{}{
+								map[string]interface{}{"type": "cal", "anchor_id": "1"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestVerifySignature_CHPPath(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := signedCHPProof(t, key, chpPathHash)
+
+	res, err := VerifySignature(p, NewKeySet().AddKey("other", &other.PublicKey).AddKey("service", &key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Verified || res.KeyId != "service" {
+		t.Fatal("signature should have been verified by the service key")
+	}
+	if res.Signed != chpPathHash {
+		t.Fatalf("unexpected signed value '%s'", res.Signed)
+	}
+
+	res, err = VerifySignature(p, NewKeySet().AddKey("other", &other.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Verified {
+		t.Fatal("signature should not have been verified by an untrusted key")
+	}
+}
+
+// The service fixtures are signed by the service key, which isn't bundled, so only the extraction
+// of their signatures is checked.
+func TestVerifySignature_ServiceFixtures(t *testing.T) {
+	data, err := DecodeProof(chpPathSignedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifySignature(&AnchorProof{Format: Proof_CHP_PATH_SIGNED, Data: data}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Signed != chpPathHash || len(res.Signature) != 256 || res.Verified {
+		t.Fatal("unexpected CHP_PATH_SIGNED signature")
+	}
+
+	data, err = DecodeProof(ethTrieSignedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = VerifySignature(&AnchorProof{Format: Proof_ETH_TRIE_SIGNED, Hash: ethTrieHash, Data: data}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Signed != "c48ff10b5b8c4ecd2dbdef271a8fc5043870e3b0ed02a54ff9ceeb25cb4761f0" || len(res.Signature) != 256 {
+		t.Fatal("unexpected ETH_TRIE_SIGNED signature")
+	}
+}
+
+func TestVerifySignature_SignedFixture(t *testing.T) {
+	data, err := DecodeProof(testSignedCHPPathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &AnchorProof{Format: Proof_CHP_PATH_SIGNED, Hash: chpPathHash, Data: data}
+	keys := NewKeySet()
+	if err := keys.AddPEM("service", []byte(testSigningKey)); err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifySignature(p, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Verified || res.KeyId != "service" || res.Signed != chpPathHash || res.Certificate != nil {
+		t.Fatal("signature should have been verified by the test signing key")
+	}
+
+	// Tampering with the signed value invalidates the signature.
+	data["hash"] = chpPathHash[:62] + "00"
+	res, err = VerifySignature(p, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Verified {
+		t.Fatal("signature of a modified proof should not have been verified")
+	}
+}
+
+func TestVerifySignature_Certificate(t *testing.T) {
+	data, err := DecodeProof(testCertSignedCHPPathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &AnchorProof{Format: Proof_CHP_PATH_SIGNED, Hash: chpPathHash, Data: data}
+
+	// The signing certificate is extracted even without trusted keys.
+	res, err := VerifySignature(p, NewKeySet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Certificate == nil || res.Certificate.Subject.CommonName != "Anchor Test Signer" || res.Verified {
+		t.Fatal("signing certificate should have been extracted but not trusted")
+	}
+
+	// The certificate is trusted as it is issued by the trusted CA.
+	keys := NewKeySet()
+	if err := keys.AddPEM("ca", []byte(testSigningCA)); err != nil {
+		t.Fatal(err)
+	}
+	res, err = VerifySignature(p, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Verified || res.KeyId != "CN=Anchor Test Signer" {
+		t.Fatalf("signature should have been verified by the signing certificate, got '%s'", res.KeyId)
+	}
+
+	// A trusted key which didn't make the signature doesn't verify it.
+	keys = NewKeySet()
+	if err := keys.AddPEM("service", []byte(testSigningKey)); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = VerifySignature(p, keys); err != nil || res.Verified {
+		t.Fatal("signature should not have been verified by an untrusted certificate")
+	}
+}
+
+func TestVerifySignature_Unsigned(t *testing.T) {
+	data, err := DecodeProof(chpPathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySignature(&AnchorProof{Format: Proof_CHP_PATH, Data: data}, nil); err == nil {
+		t.Fatal("expected unsigned format error")
+	}
+	if _, err := VerifySignature(&AnchorProof{Format: Proof_CHP_PATH_SIGNED, Data: data}, nil); err == nil {
+		t.Fatal("expected missing signature error")
+	}
+}
+
+func TestKeySet_AddPEM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewKeySet()
+	if err := keys.AddPEM("service", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})); err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifySignature(signedCHPProof(t, key, chpPathHash), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Verified {
+		t.Fatal("signature should have been verified")
+	}
+	if err := keys.AddPEM("invalid", []byte("not pem")); err == nil {
+		t.Fatal("expected invalid PEM error")
+	}
+}
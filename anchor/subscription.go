@@ -0,0 +1,402 @@
+package anchor
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SubscribeOptions represents the subscription options.
+type SubscribeOptions struct {
+	// The batch ID to filter on. Empty subscribes to all batches of the anchor type.
+	BatchId string
+	// The maximum consecutive attempts to resume a failed stream. Zero disables resumption.
+	MaxRetries int
+	// The delay before the first attempt to resume a failed stream, doubled on each attempt.
+	MinBackoff time.Duration
+	// The maximum delay between attempts to resume a failed stream.
+	MaxBackoff time.Duration
+}
+
+// SubscribeOption func.
+type SubscribeOption func(*SubscribeOptions)
+
+// SubscribeWithBatchId filters the subscription to a single batch.
+func SubscribeWithBatchId(batchId string) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.BatchId = batchId
+	}
+}
+
+// SubscribeWithMaxRetries sets the maximum consecutive attempts to resume a failed stream.
+func SubscribeWithMaxRetries(retries int) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.MaxRetries = retries
+	}
+}
+
+// SubscribeWithBackoff sets the minimum and maximum delays between attempts to resume a
+// failed stream.
+func SubscribeWithBackoff(min time.Duration, max time.Duration) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.MinBackoff = min
+		o.MaxBackoff = max
+	}
+}
+
+// Returns the subscribe options with the defaults applied.
+func newSubscribeOptions(opts ...SubscribeOption) *SubscribeOptions {
+	o := &SubscribeOptions{
+		MaxRetries: 5,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Returns whether a failed stream can be resumed.
+func resumable(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unknown, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscription holds the state shared by all subscriptions.
+type subscription struct {
+	closed chan struct{}
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func newSubscription(cancel context.CancelFunc) subscription {
+	return subscription{
+		closed: make(chan struct{}),
+		cancel: cancel,
+	}
+}
+
+// Close unsubscribes. No further updates are delivered and the subscription's channel is closed.
+func (s *subscription) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+		s.cancel()
+	})
+}
+
+// isClosed returns whether the subscription was closed.
+func (s *subscription) isClosed() bool {
+	select {
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProofUpdate represents a status update of a subscribed proof.
+type ProofUpdate struct {
+	// The updated proof. Nil when the update is an error.
+	Proof *AnchorProof
+	// The status of the proof's batch.
+	Status Batch_Status
+	// The proof's batch, holding the timestamps of each status transition.
+	Batch *Batch
+	// The error that ended the subscription, including batch errors.
+	Err error
+}
+
+// Final returns whether this is the terminal update of the subscription.
+func (u *ProofUpdate) Final() bool {
+	return u.Err != nil || u.Status == Batch_CONFIRMED || u.Status == Batch_ERROR
+}
+
+// ProofSubscription delivers the status updates of a single proof. Exactly one final update is
+// delivered (the proof is CONFIRMED, the batch errored, or the subscription failed) before the
+// updates channel is closed, unless the subscription is closed first.
+type ProofSubscription struct {
+	subscription
+	updates chan *ProofUpdate
+}
+
+// Updates returns the channel of proof updates.
+func (s *ProofSubscription) Updates() <-chan *ProofUpdate {
+	return s.updates
+}
+
+// Delivers the update, returning false if the subscription was closed.
+func (s *ProofSubscription) send(u *ProofUpdate) bool {
+	if s.isClosed() {
+		return false
+	}
+	select {
+	case s.updates <- u:
+		return true
+	case <-s.closed:
+		return false
+	}
+}
+
+// WatchProof subscribes to the status updates of the proof with the given ID. The subscription
+// ends once the proof is CONFIRMED, its batch errors, or the context is done. Dropped streams are
+// resumed as configured by the options, re-fetching the proof's state so no status transition is
+// missed.
+//
+// The anchor type may be nil if the ID includes it.
+func (c *Client) WatchProof(ctx context.Context, id string, anchorType interface{}, opts ...SubscribeOption) (*ProofSubscription, error) {
+	p, err := ParseProofID(id)
+	if err != nil {
+		return nil, err
+	}
+	at, err := p.anchorType(anchorType)
+	if err != nil {
+		return nil, err
+	}
+	return c.watchProof(ctx, p, at, opts...)
+}
+
+// WatchProofByID is WatchProof for a proof ID including the anchor type.
+func (c *Client) WatchProofByID(ctx context.Context, id ProofID, opts ...SubscribeOption) (*ProofSubscription, error) {
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	at, err := id.anchorType(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.watchProof(ctx, id, at, opts...)
+}
+
+func (c *Client) watchProof(ctx context.Context, id ProofID, at Anchor_Type, opts ...SubscribeOption) (*ProofSubscription, error) {
+	o := newSubscribeOptions(opts...)
+	ctx, cancel := context.WithCancel(ctx)
+	batches, err := c.SubscribeBatches(ctx, at, append(opts, SubscribeWithBatchId(id.BatchId))...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	sub := &ProofSubscription{
+		subscription: newSubscription(cancel),
+		updates:      make(chan *ProofUpdate),
+	}
+	go func() {
+		// Release the subscription's context once the watch ends, even if it is never closed.
+		defer cancel()
+		defer close(sub.updates)
+		defer batches.Close()
+		for e := range batches.Events() {
+			if e.Err != nil {
+				if e.Err == io.EOF {
+					e.Err = ErrSubscriptionEnded
+				}
+				sub.send(&ProofUpdate{Err: e.Err})
+				return
+			}
+			u := &ProofUpdate{Status: e.Status, Batch: e.Batch}
+			if e.Status == Batch_ERROR {
+				u.Err = &BatchError{Batch: e.Batch}
+			} else if proof, err := c.getProofWithRetry(ctx, id, at, o); err != nil {
+				u.Err = err
+			} else {
+				u.Proof = proof
+			}
+			if !sub.send(u) || u.Final() {
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Gets the proof, retrying failures the subscription would resume from with its backoff.
+func (c *Client) getProofWithRetry(ctx context.Context, id ProofID, at Anchor_Type, o *SubscribeOptions) (*AnchorProof, error) {
+	b := &backoff{min: o.MinBackoff, max: o.MaxBackoff}
+	for {
+		proof, err := c.getProof(ctx, id, at)
+		if err == nil || ctx.Err() != nil || !resumable(err) || b.attempts >= o.MaxRetries {
+			return proof, err
+		}
+		if err := sleep(ctx, b.next()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// BatchEvent represents a status update of a batch.
+type BatchEvent struct {
+	Id          string       // the batch ID
+	AnchorType  Anchor_Type  // the batch's anchor type
+	ProofFormat Proof_Format // the batch's proof format
+	Status      Batch_Status // the batch status
+	Error       string       // the batch error, if the status is ERROR
+	Size        int64        // the number of hashes in the batch
+	Hash        string       // the batch's root hash
+	CreatedAt   time.Time    // when the batch was created
+	FlushedAt   time.Time    // when the batch stopped batching
+	StartedAt   time.Time    // when the batch started processing
+	SubmittedAt time.Time    // when the batch root was submitted to the anchor
+	FinalizedAt time.Time    // when the batch root was confirmed by the anchor
+	Batch       *Batch       // the batch as received from the anchor service
+	// The error that ended the subscription. All other fields are empty when set.
+	Err error
+}
+
+// Converts the batch to an event.
+func newBatchEvent(b *Batch) *BatchEvent {
+	return &BatchEvent{
+		Id:          b.GetId(),
+		AnchorType:  b.GetAnchorType(),
+		ProofFormat: b.GetProofFormat(),
+		Status:      b.GetStatus(),
+		Error:       b.GetError(),
+		Size:        b.GetSize(),
+		Hash:        b.GetHash(),
+		CreatedAt:   toTime(b.GetCreatedAt()),
+		FlushedAt:   toTime(b.GetFlushedAt()),
+		StartedAt:   toTime(b.GetStartedAt()),
+		SubmittedAt: toTime(b.GetSubmittedAt()),
+		FinalizedAt: toTime(b.GetFinalizedAt()),
+		Batch:       b,
+	}
+}
+
+// Converts the timestamp to time, returning the zero time if unset.
+func toTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// BatchSubscription delivers the status updates of batches. Failed streams are resumed until the
+// retry budget is exhausted, at which point a final event holding the error is delivered.
+type BatchSubscription struct {
+	subscription
+	events chan *BatchEvent
+}
+
+// Events returns the channel of batch events.
+func (s *BatchSubscription) Events() <-chan *BatchEvent {
+	return s.events
+}
+
+// Delivers the event, returning false if the subscription was closed.
+func (s *BatchSubscription) send(e *BatchEvent) bool {
+	if s.isClosed() {
+		return false
+	}
+	select {
+	case s.events <- e:
+		return true
+	case <-s.closed:
+		return false
+	}
+}
+
+// SubscribeBatches subscribes to the status updates of the batches of the anchor type. All batches
+// are watched unless filtered with SubscribeWithBatchId, in which case the subscription ends once
+// the batch is CONFIRMED or errors.
+func (c *Client) SubscribeBatches(ctx context.Context, anchorType Anchor_Type, opts ...SubscribeOption) (*BatchSubscription, error) {
+	o := newSubscribeOptions(opts...)
+	ctx, cancel := context.WithCancel(ctx)
+	req := &SubscribeBatchesRequest{
+		Filter: &BatchRequest{
+			BatchId:    o.BatchId,
+			AnchorType: anchorType,
+		},
+	}
+	stream, err := c.anchor.SubscribeBatches(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, toError(err)
+	}
+	sub := &BatchSubscription{
+		subscription: newSubscription(cancel),
+		events:       make(chan *BatchEvent),
+	}
+	go func() {
+		defer close(sub.events)
+		defer cancel()
+		b := &backoff{min: o.MinBackoff, max: o.MaxBackoff}
+		last := Batch_Status(-1)
+		catchUp := false
+		// Handles a stream failure, returning whether the stream should be resumed.
+		retry := func(err error) bool {
+			if ctx.Err() != nil {
+				sub.send(&BatchEvent{Err: ctx.Err()})
+				return false
+			}
+			if !resumable(err) || b.attempts >= o.MaxRetries {
+				sub.send(&BatchEvent{Err: toError(err)})
+				return false
+			}
+			if err := sleep(ctx, b.next()); err != nil {
+				sub.send(&BatchEvent{Err: err})
+				return false
+			}
+			return true
+		}
+		for {
+			if stream == nil {
+				resumed, err := c.anchor.SubscribeBatches(ctx, req)
+				if err != nil {
+					if !retry(err) {
+						return
+					}
+					continue
+				}
+				stream = resumed
+				catchUp = o.BatchId != ""
+			}
+			// Catch up on any status change of the filtered batch missed while disconnected.
+			if catchUp {
+				batch, err := c.anchor.GetBatch(ctx, req.Filter)
+				if err != nil {
+					if !retry(err) {
+						return
+					}
+					continue
+				}
+				catchUp = false
+				if batch.GetStatus() != last {
+					last = batch.GetStatus()
+					if !sub.send(newBatchEvent(batch)) || batchDone(last) {
+						return
+					}
+				}
+			}
+			batch, err := stream.Recv()
+			if err != nil {
+				stream = nil
+				if !retry(err) {
+					return
+				}
+				continue
+			}
+			b.reset()
+			last = batch.GetStatus()
+			if !sub.send(newBatchEvent(batch)) || (o.BatchId != "" && batchDone(last)) {
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Returns whether the batch status is terminal.
+func batchDone(s Batch_Status) bool {
+	return s == Batch_CONFIRMED || s == Batch_ERROR
+}
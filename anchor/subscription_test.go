@@ -0,0 +1,287 @@
+package anchor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeBatchStream returns the batches in order, followed by the error.
+type fakeBatchStream struct {
+	grpc.ClientStream
+	batches []*Batch
+	err     error
+}
+
+func (s *fakeBatchStream) Recv() (*Batch, error) {
+	if len(s.batches) == 0 {
+		return nil, s.err
+	}
+	b := s.batches[0]
+	s.batches = s.batches[1:]
+	return b, nil
+}
+
+// fakeAnchorClient serves a new stream on each subscribe and a fixed batch and proof, after
+// returning the queued errors of each call.
+type fakeAnchorClient struct {
+	AnchorServiceClient
+	streams   []*fakeBatchStream
+	batch     *Batch
+	batchErrs []error
+	proof     *Proof
+	proofErrs []error
+}
+
+func (c *fakeAnchorClient) SubscribeBatches(ctx context.Context, in *SubscribeBatchesRequest, opts ...grpc.CallOption) (AnchorService_SubscribeBatchesClient, error) {
+	if len(c.streams) == 0 {
+		return nil, status.Error(codes.Unavailable, "no streams left")
+	}
+	s := c.streams[0]
+	c.streams = c.streams[1:]
+	return s, nil
+}
+
+func (c *fakeAnchorClient) GetBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*Batch, error) {
+	if len(c.batchErrs) > 0 {
+		err := c.batchErrs[0]
+		c.batchErrs = c.batchErrs[1:]
+		return nil, err
+	}
+	return c.batch, nil
+}
+
+func (c *fakeAnchorClient) GetProof(ctx context.Context, in *ProofRequest, opts ...grpc.CallOption) (*Proof, error) {
+	if len(c.proofErrs) > 0 {
+		err := c.proofErrs[0]
+		c.proofErrs = c.proofErrs[1:]
+		return nil, err
+	}
+	return c.proof, nil
+}
+
+// Collects all the events of the subscription.
+func collectBatchEvents(t *testing.T, sub *BatchSubscription) []*BatchEvent {
+	events := make([]*BatchEvent, 0)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		case <-timeout:
+			t.Fatal("timed out waiting for events")
+		}
+	}
+}
+
+func TestClient_SubscribeBatches_Resume(t *testing.T) {
+	created := time.Date(2021, 3, 25, 23, 41, 13, 0, time.UTC)
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING, CreatedAt: timestamppb.New(created)}}, err: status.Error(codes.Unavailable, "")},
+			{batches: []*Batch{{Id: "1", Status: Batch_QUEUING}}, err: status.Error(codes.PermissionDenied, "")},
+		},
+	}}
+	sub, err := client.SubscribeBatches(context.Background(), Anchor_ETH, SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := collectBatchEvents(t, sub)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].Status != Batch_BATCHING || !events[0].CreatedAt.Equal(created) || !events[0].FlushedAt.IsZero() {
+		t.Fatal("unexpected first event")
+	}
+	if events[1].Status != Batch_QUEUING {
+		t.Fatal("unexpected second event")
+	}
+	if status.Code(events[2].Err) != codes.PermissionDenied {
+		t.Fatalf("expected permission denied, got %v", events[2].Err)
+	}
+}
+
+func TestClient_SubscribeBatches_RetriesExhausted(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{err: status.Error(codes.Unavailable, "")},
+			{err: status.Error(codes.Unavailable, "")},
+			{err: status.Error(codes.Unavailable, "")},
+		},
+	}}
+	sub, err := client.SubscribeBatches(context.Background(), Anchor_ETH,
+		SubscribeWithMaxRetries(1), SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := collectBatchEvents(t, sub)
+	if len(events) != 1 || status.Code(events[0].Err) != codes.Unavailable {
+		t.Fatal("expected a single unavailable error")
+	}
+}
+
+func TestClient_SubscribeBatches_CatchUp(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING}}, err: status.Error(codes.Unavailable, "")},
+			{batches: []*Batch{{Id: "1", Status: Batch_CONFIRMED}}},
+		},
+		batch: &Batch{Id: "1", Status: Batch_PENDING},
+	}}
+	sub, err := client.SubscribeBatches(context.Background(), Anchor_ETH,
+		SubscribeWithBatchId("1"), SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := collectBatchEvents(t, sub)
+	exp := []Batch_Status{Batch_BATCHING, Batch_PENDING, Batch_CONFIRMED}
+	if len(events) != len(exp) {
+		t.Fatalf("expected %d events, got %d", len(exp), len(events))
+	}
+	for i, e := range events {
+		if e.Err != nil || e.Status != exp[i] {
+			t.Fatalf("unexpected event %d", i)
+		}
+	}
+}
+
+func TestClient_SubscribeBatches_CatchUpErrors(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING}}, err: status.Error(codes.Unavailable, "")},
+			{batches: []*Batch{{Id: "1", Status: Batch_CONFIRMED}}},
+		},
+		batch:     &Batch{Id: "1", Status: Batch_PENDING},
+		batchErrs: []error{status.Error(codes.Unavailable, ""), status.Error(codes.NotFound, "")},
+	}}
+	sub, err := client.SubscribeBatches(context.Background(), Anchor_ETH,
+		SubscribeWithBatchId("1"), SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := collectBatchEvents(t, sub)
+	if len(events) != 2 || events[0].Status != Batch_BATCHING || status.Code(events[1].Err) != codes.NotFound {
+		t.Fatal("expected the catch up error to end the subscription")
+	}
+}
+
+func TestClient_SubscribeBatches_Close(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING}, {Id: "2", Status: Batch_BATCHING}}},
+		},
+	}}
+	sub, err := client.SubscribeBatches(context.Background(), Anchor_ETH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-sub.Events()
+	sub.Close()
+	for range sub.Events() {
+	}
+}
+
+func TestClient_WatchProof_Resume(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING}}, err: status.Error(codes.Unavailable, "")},
+			{batches: []*Batch{{Id: "1", Status: Batch_CONFIRMED}}},
+		},
+		batch: &Batch{Id: "1", Status: Batch_PENDING},
+		proof: &Proof{Hash: "ab", BatchId: "1", BatchStatus: Batch_CONFIRMED},
+	}}
+	sub, err := client.WatchProof(context.Background(), "ab:1", Anchor_ETH, SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Batch_Status{Batch_BATCHING, Batch_PENDING, Batch_CONFIRMED}
+	i := 0
+	for u := range sub.Updates() {
+		if u.Err != nil {
+			t.Fatal(u.Err)
+		}
+		if i >= len(exp) || u.Status != exp[i] {
+			t.Fatalf("unexpected update %d", i)
+		}
+		if u.Proof == nil || u.Proof.Id != "ab:1" {
+			t.Fatal("expected the updated proof")
+		}
+		i++
+	}
+	if i != len(exp) {
+		t.Fatalf("expected %d updates, got %d", len(exp), i)
+	}
+}
+
+func TestClient_WatchProof_RetriesExhausted(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING}}, err: status.Error(codes.Unavailable, "")},
+		},
+		proof: &Proof{Hash: "ab", BatchId: "1", BatchStatus: Batch_BATCHING},
+	}}
+	sub, err := client.WatchProof(context.Background(), "ab:1", Anchor_ETH,
+		SubscribeWithMaxRetries(2), SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last *ProofUpdate
+	n := 0
+	for u := range sub.Updates() {
+		last = u
+		n++
+	}
+	if n != 2 || status.Code(last.Err) != codes.Unavailable {
+		t.Fatal("expected an update followed by a single unavailable error")
+	}
+}
+
+func TestClient_WatchProof_GetProofRetry(t *testing.T) {
+	client := &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING}, {Id: "1", Status: Batch_CONFIRMED}}},
+		},
+		proof:     &Proof{Hash: "ab", BatchId: "1", BatchStatus: Batch_CONFIRMED},
+		proofErrs: []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, "")},
+	}}
+	sub, err := client.WatchProof(context.Background(), "ab:1", Anchor_ETH, SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for u := range sub.Updates() {
+		if u.Err != nil || u.Proof == nil {
+			t.Fatalf("expected the proof after retrying, got %v", u.Err)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 updates, got %d", n)
+	}
+
+	client = &Client{anchor: &fakeAnchorClient{
+		streams: []*fakeBatchStream{
+			{batches: []*Batch{{Id: "1", Status: Batch_BATCHING}}, err: status.Error(codes.PermissionDenied, "")},
+		},
+		proof:     &Proof{Hash: "ab", BatchId: "1", BatchStatus: Batch_BATCHING},
+		proofErrs: []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, "")},
+	}}
+	sub, err = client.WatchProof(context.Background(), "ab:1", Anchor_ETH,
+		SubscribeWithMaxRetries(1), SubscribeWithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := <-sub.Updates()
+	if !u.Final() || status.Code(u.Err) != codes.Unavailable {
+		t.Fatal("expected a final unavailable error once the retries are exhausted")
+	}
+	sub.Close()
+}
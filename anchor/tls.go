@@ -0,0 +1,43 @@
+package anchor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// Builds the TLS configuration of a secure connection. The server certificate is verified against
+// the system roots unless root CAs are provided or verification is explicitly skipped.
+func (o *ClientOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		RootCAs:            o.RootCAs,
+		ServerName:         o.ServerName,
+		Certificates:       append([]tls.Certificate{}, o.Certificates...),
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if o.RootCAFile != "" {
+		b, err := ioutil.ReadFile(o.RootCAFile)
+		if err != nil {
+			return nil, err
+		}
+		// Append to a copy so the caller's pool is left unchanged.
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		} else {
+			config.RootCAs = config.RootCAs.Clone()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in root CA file '%s'", o.RootCAFile)
+		}
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+	return config, nil
+}
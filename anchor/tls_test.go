@@ -0,0 +1,175 @@
+package anchor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// Creates a self-signed certificate for the name, returning it along with its PEM encoding.
+func newTestCertificate(t *testing.T, name string) (tls.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{name},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, certPEM, keyPEM
+}
+
+// Starts a TLS anchor server which implements no methods, returning its address.
+func startTLSServer(t *testing.T, config *tls.Config) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)))
+	RegisterAnchorServiceServer(server, &UnimplementedAnchorServiceServer{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+// Calls the server, returning the status code. Unimplemented means the handshake succeeded.
+func callTLSServer(t *testing.T, opts ...ClientOption) codes.Code {
+	client, err := Connect(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = client.GetAnchor(ctx, Anchor_ETH)
+	return status.Code(err)
+}
+
+func TestConnect_TLS(t *testing.T) {
+	cert, certPEM, _ := newTestCertificate(t, "anchor.test")
+	addr := startTLSServer(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+
+	// The self-signed certificate is not trusted by the system roots.
+	if code := callTLSServer(t, WithAddress(addr), WithServerName("anchor.test")); code != codes.Unavailable {
+		t.Fatalf("expected the untrusted certificate to be rejected, got %s", code)
+	}
+	if code := callTLSServer(t, WithAddress(addr), WithRootCAs(pool)); code != codes.Unavailable {
+		t.Fatalf("expected the server name mismatch to be rejected, got %s", code)
+	}
+	if code := callTLSServer(t, WithAddress(addr), WithRootCAs(pool), WithServerName("anchor.test")); code != codes.Unimplemented {
+		t.Fatalf("expected the trusted certificate to be accepted, got %s", code)
+	}
+	if code := callTLSServer(t, WithAddress(addr), WithInsecureSkipVerify(true)); code != codes.Unimplemented {
+		t.Fatalf("expected verification to be skipped, got %s", code)
+	}
+}
+
+func TestConnect_MutualTLS(t *testing.T) {
+	serverCert, serverPEM, _ := newTestCertificate(t, "anchor.test")
+	clientCert, clientPEM, clientKeyPEM := newTestCertificate(t, "client.test")
+	clients := x509.NewCertPool()
+	clients.AppendCertsFromPEM(clientPEM)
+	addr := startTLSServer(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clients,
+	})
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	caFile := write("ca.pem", serverPEM)
+	certFile := write("client.pem", clientPEM)
+	keyFile := write("client.key", clientKeyPEM)
+
+	if code := callTLSServer(t, WithAddress(addr), WithRootCAFile(caFile), WithServerName("anchor.test")); code == codes.Unimplemented {
+		t.Fatal("expected the connection without a client certificate to be rejected")
+	}
+	if code := callTLSServer(t, WithAddress(addr), WithRootCAFile(caFile), WithServerName("anchor.test"),
+		WithClientCertificateFile(certFile, keyFile)); code != codes.Unimplemented {
+		t.Fatalf("expected the client certificate file to be accepted, got %s", code)
+	}
+	if code := callTLSServer(t, WithAddress(addr), WithRootCAFile(caFile), WithServerName("anchor.test"),
+		WithClientCertificate(clientCert)); code != codes.Unimplemented {
+		t.Fatalf("expected the client certificate to be accepted, got %s", code)
+	}
+}
+
+func TestConnect_InvalidRootCAFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(file, []byte("not pem"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Connect(WithRootCAFile(file)); err == nil {
+		t.Fatal("expected an invalid root CA file error")
+	}
+	if _, err := Connect(WithRootCAFile(filepath.Join(t.TempDir(), "missing.pem"))); err == nil {
+		t.Fatal("expected a missing root CA file error")
+	}
+}
+
+func TestClientOptions_TLSConfig_RootCAs(t *testing.T) {
+	_, caPEM, _ := newTestCertificate(t, "localhost")
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(file, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	o := &ClientOptions{}
+	WithRootCAs(pool)(o)
+	WithRootCAFile(file)(o)
+	config, err := o.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pool.Equal(x509.NewCertPool()) {
+		t.Fatal("the root CA file should not be appended to the caller's pool")
+	}
+	if config.RootCAs.Equal(pool) {
+		t.Fatal("expected the root CA file in the connection's pool")
+	}
+}
@@ -1,7 +1,8 @@
 package anchor
 
 import (
-	"errors"
+	"crypto"
+	"encoding/hex"
 	"fmt"
 )
 
@@ -19,10 +20,65 @@ func generateProofId(hash string, batchId string) string {
 func getAnchorType(anchorType interface{}) (Anchor_Type, error) {
 	switch anchorType.(type) {
 	case string:
-		return Anchor_Type(Anchor_Type_value[anchorType.(string)]), nil
+		t, ok := Anchor_Type_value[anchorType.(string)]
+		if !ok {
+			return Anchor_ETH, fmt.Errorf("%w '%s'", ErrInvalidAnchorType, anchorType.(string))
+		}
+		return Anchor_Type(t), nil
 	case Anchor_Type:
 		return anchorType.(Anchor_Type), nil
 	default:
-		return Anchor_ETH, errors.New("invalid anchorType provided")
+		return Anchor_ETH, ErrInvalidAnchorType
 	}
 }
+
+// Retrieves the proof format from either string or proto.Proof_Format
+func getProofFormat(format interface{}) (Proof_Format, error) {
+	switch format.(type) {
+	case string:
+		f, ok := Proof_Format_value[format.(string)]
+		if !ok {
+			return Proof_CHP_PATH, fmt.Errorf("%w '%s'", ErrUnsupportedFormat, format.(string))
+		}
+		return Proof_Format(f), nil
+	case Proof_Format:
+		return format.(Proof_Format), nil
+	default:
+		return Proof_CHP_PATH, ErrUnsupportedFormat
+	}
+}
+
+// Retrieves the batch status from its string form.
+func getBatchStatus(status string) (Batch_Status, error) {
+	s, ok := Batch_Status_value[status]
+	if !ok {
+		return Batch_BATCHING, fmt.Errorf("invalid batch status '%s'", status)
+	}
+	return Batch_Status(s), nil
+}
+
+// Checks the hash is lowercase hex, and when the algorithm is known, that it is the length of
+// the algorithm's digest.
+func validateHash(hash string, algorithm crypto.Hash) error {
+	if hash == "" {
+		return fmt.Errorf("%w: hash is empty", ErrInvalidHash)
+	}
+	for _, c := range hash {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return fmt.Errorf("%w '%s': must be lowercase hex", ErrInvalidHash, hash)
+		}
+	}
+	if len(hash)%2 != 0 {
+		return fmt.Errorf("%w '%s': must have an even length", ErrInvalidHash, hash)
+	}
+	if algorithm == 0 {
+		return nil
+	}
+	if !algorithm.Available() {
+		return fmt.Errorf("%w '%s': unsupported algorithm %d", ErrInvalidHash, hash, algorithm)
+	}
+	if len(hash) != hex.EncodedLen(algorithm.Size()) {
+		return fmt.Errorf("%w '%s': expected %d hex characters, got %d", ErrInvalidHash, hash, hex.EncodedLen(algorithm.Size()), len(hash))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package anchor
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateHash(t *testing.T) {
+	sha256 := strings.Repeat("ab", 32)
+	tests := []struct {
+		hash      string
+		algorithm crypto.Hash
+		valid     bool
+	}{
+		{sha256, 0, true},
+		{sha256, crypto.SHA256, true},
+		{sha256, crypto.SHA512, false},
+		{strings.Repeat("ab", 64), crypto.SHA512, true},
+		{strings.ToUpper(sha256), 0, false},
+		{"abc", 0, false},
+		{"xyz0", 0, false},
+		{"", 0, false},
+		{sha256, crypto.Hash(999), false}, // unknown
+		{sha256, crypto.MD4, false},       // not linked
+	}
+	for _, test := range tests {
+		err := validateHash(test.hash, test.algorithm)
+		if test.valid && err != nil {
+			t.Fatalf("expected '%s' to be valid: %s", test.hash, err.Error())
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidHash) {
+			t.Fatalf("expected '%s' to be invalid", test.hash)
+		}
+	}
+}
+
+func TestClient_SubmitProof_InvalidHash(t *testing.T) {
+	// The fake does not implement SubmitProof, so any RPC would panic.
+	client := &Client{anchor: &fakeAnchorClient{}}
+	_, err := client.SubmitProof(context.Background(), strings.Repeat("ab", 32), SubmitProofWithAlgorithm(crypto.SHA384))
+	if !errors.Is(err, ErrInvalidHash) {
+		t.Fatalf("expected an invalid hash error, got %v", err)
+	}
+	if _, err := client.SubmitDigest(context.Background(), []byte{1, 2, 3}, SubmitProofWithAlgorithm(crypto.SHA256)); !errors.Is(err, ErrInvalidHash) {
+		t.Fatalf("expected an invalid digest error, got %v", err)
+	}
+}
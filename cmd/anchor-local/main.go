@@ -0,0 +1,70 @@
+// Command anchor-local runs a local anchor service which anchors into an append-only log file,
+// for running the anchoring workflow without network access or an API key.
+//
+// Connect to it with anchor.Connect(anchor.WithAddress("localhost:10008"), anchor.WithInsecure(true)).
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor/local"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:10008", "the address to listen on")
+	logPath := flag.String("log", "anchor.log", "the path of the anchor log, empty to keep it in memory")
+	interval := flag.Duration("batch-interval", local.DefaultBatchInterval, "the time between each batch flush")
+	size := flag.Int("batch-size", 0, "the number of hashes which flushes a batch immediately, 0 for no limit")
+	shutdown := flag.Duration("shutdown-timeout", 5*time.Second, "the time given to open calls to finish on shutdown")
+	flag.Parse()
+
+	service, err := local.NewService(
+		local.WithLogPath(*logPath),
+		local.WithBatchInterval(*interval),
+		local.WithBatchSize(*size))
+	if err != nil {
+		log.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := grpc.NewServer()
+	service.Register(server)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-signals
+		// Batch subscriptions never end on their own, so open calls are only given until the
+		// timeout to finish before they are cancelled.
+		graceful := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(graceful)
+		}()
+		select {
+		case <-graceful:
+		case <-time.After(*shutdown):
+			server.Stop()
+		}
+	}()
+
+	log.Printf("anchoring into '%s', listening on %s", *logPath, lis.Addr())
+	if err := server.Serve(lis); err != nil {
+		log.Fatal(err)
+	}
+	<-stopped
+	if err := service.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,271 @@
+package merkle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+)
+
+// ErrBatcherClosed is returned when adding a hash to a closed batcher.
+var ErrBatcherClosed = errors.New("batcher is closed")
+
+// Submitter submits a hash to be anchored. It is implemented by *anchor.Client.
+type Submitter interface {
+	SubmitProof(ctx context.Context, hash string, opts ...anchor.SubmitProofOption) (*anchor.AnchorProof, error)
+}
+
+// BatcherOptions represents the batcher options.
+type BatcherOptions struct {
+	// The algorithm used to build each batch's tree.
+	Algorithm Hash
+	// The number of hashes which submits a batch immediately.
+	MaxSize int
+	// The maximum time a hash waits before its batch is submitted.
+	MaxDelay time.Duration
+	// The maximum time a batch's submission may take, or zero for no limit.
+	SubmitTimeout time.Duration
+	// The label of the branch added to each proof holding the path from the hash to the root.
+	Label string
+	// The options used to submit each batch's root.
+	SubmitOptions []anchor.SubmitProofOption
+}
+
+// BatcherOption func.
+type BatcherOption func(*BatcherOptions)
+
+// BatcherWithAlgorithm sets the algorithm used to build each batch's tree.
+func BatcherWithAlgorithm(algorithm Hash) BatcherOption {
+	return func(o *BatcherOptions) {
+		o.Algorithm = algorithm
+	}
+}
+
+// BatcherWithMaxSize sets the number of hashes which submits a batch immediately.
+func BatcherWithMaxSize(size int) BatcherOption {
+	return func(o *BatcherOptions) {
+		o.MaxSize = size
+	}
+}
+
+// BatcherWithMaxDelay sets the maximum time a hash waits before its batch is submitted.
+func BatcherWithMaxDelay(d time.Duration) BatcherOption {
+	return func(o *BatcherOptions) {
+		o.MaxDelay = d
+	}
+}
+
+// BatcherWithSubmitTimeout sets the maximum time a batch's submission may take, including awaiting
+// its confirmation.
+func BatcherWithSubmitTimeout(d time.Duration) BatcherOption {
+	return func(o *BatcherOptions) {
+		o.SubmitTimeout = d
+	}
+}
+
+// BatcherWithLabel sets the label of the branch holding the path from the hash to the root.
+func BatcherWithLabel(label string) BatcherOption {
+	return func(o *BatcherOptions) {
+		o.Label = label
+	}
+}
+
+// BatcherWithSubmitOptions sets the options used to submit each batch's root, e.g. the anchor
+// type or anchor.SubmitProofWithAwaitConfirmed.
+func BatcherWithSubmitOptions(opts ...anchor.SubmitProofOption) BatcherOption {
+	return func(o *BatcherOptions) {
+		o.SubmitOptions = opts
+	}
+}
+
+// Batcher accumulates hashes into a tree and submits only the tree's root, handing each caller
+// back the root's proof extended with the path from their hash to the root. A batch is submitted
+// once it holds MaxSize hashes or its first hash has waited MaxDelay.
+//
+// The root is submitted with the configured submit options, so proofs only hold the anchors once
+// the root is confirmed (see anchor.SubmitProofWithAwaitConfirmed). Only CHP_PATH formats can be
+// extended with a path.
+type Batcher struct {
+	submitter Submitter
+	opts      *BatcherOptions
+	ctx       context.Context // cancelled once Close gives up waiting for the submissions
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	pending *batch
+	closed  bool
+}
+
+// batch holds the hashes waiting to be submitted together, and the result once submitted.
+type batch struct {
+	hashes []string
+	index  map[string]bool
+	timer  *time.Timer
+	done   chan struct{}
+	tree   *Tree
+	proof  *anchor.AnchorProof
+	err    error
+}
+
+// NewBatcher creates a new batcher submitting with the submitter.
+func NewBatcher(submitter Submitter, opts ...BatcherOption) (*Batcher, error) {
+	o := &BatcherOptions{
+		Algorithm: SHA256,
+		MaxSize:   1000,
+		MaxDelay:  time.Second,
+		Label:     "pdb_batch_branch",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.Algorithm.available() {
+		return nil, fmt.Errorf("%w '%s'", ErrUnsupportedAlgorithm, o.Algorithm)
+	}
+	if o.MaxSize <= 0 {
+		return nil, fmt.Errorf("max size %d must be positive", o.MaxSize)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Batcher{
+		submitter: submitter,
+		opts:      o,
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Add adds the hex encoded hash to the next batch, blocking until the batch is submitted, and
+// returns the proof of the hash. The hash must be the size of the batcher's algorithm.
+func (b *Batcher) Add(ctx context.Context, hash string) (*anchor.AnchorProof, error) {
+	h, err := decodeHex(hash)
+	if err != nil {
+		return nil, fmt.Errorf("hash %w", err)
+	}
+	if size := b.opts.Algorithm.Hash().Size(); len(h) != size {
+		return nil, fmt.Errorf("hash '%s' must be %d bytes for %s", hash, size, b.opts.Algorithm)
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrBatcherClosed
+	}
+	p := b.pending
+	if p == nil {
+		p = &batch{
+			index: make(map[string]bool),
+			done:  make(chan struct{}),
+		}
+		p.timer = time.AfterFunc(b.opts.MaxDelay, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.pending == p {
+				b.submit()
+			}
+		})
+		b.pending = p
+	}
+	if !p.index[hash] {
+		p.index[hash] = true
+		p.hashes = append(p.hashes, hash)
+	}
+	if len(p.hashes) >= b.opts.MaxSize {
+		b.submit()
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	proof, err := copyProof(p.proof)
+	if err != nil {
+		return nil, err
+	}
+	return p.tree.AddPathToProof(proof, hash, b.opts.Label)
+}
+
+// Flush submits the pending batch without waiting for it to fill.
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.submit()
+}
+
+// Close submits the pending batch and waits for every submission to complete. If the context is
+// done first, the submissions still in progress are cancelled and the context's error is returned.
+// Hashes added after closing are rejected.
+func (b *Batcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.submit()
+	b.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		b.cancel()
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Submits the pending batch in the background. Must be called with the lock held.
+func (b *Batcher) submit() {
+	p := b.pending
+	if p == nil {
+		return
+	}
+	b.pending = nil
+	p.timer.Stop()
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer close(p.done)
+		builder := NewBuilder(b.opts.Algorithm)
+		for _, h := range p.hashes {
+			builder.AddRaw(h, h)
+		}
+		p.tree = builder.Build()
+		ctx, cancel := b.ctx, context.CancelFunc(func() {})
+		if b.opts.SubmitTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, b.opts.SubmitTimeout)
+		}
+		defer cancel()
+		opts := append([]anchor.SubmitProofOption{anchor.SubmitProofWithAlgorithm(b.opts.Algorithm.Hash())}, b.opts.SubmitOptions...)
+		p.proof, p.err = b.submitter.SubmitProof(ctx, p.tree.GetRoot(), opts...)
+	}()
+}
+
+// Deep copies the proof so every hash's path is added to its own copy of the root's proof.
+func copyProof(p *anchor.AnchorProof) (*anchor.AnchorProof, error) {
+	c := *p
+	if p.Metadata != nil {
+		m := *p.Metadata
+		c.Metadata = &m
+	}
+	c.Data = make(map[string]interface{})
+	if p.Data != nil {
+		b, err := json.Marshal(p.Data)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &c.Data); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
@@ -0,0 +1,262 @@
+package merkle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
+)
+
+// fakeSubmitter records the submitted roots and returns a CHP_PATH proof anchoring each.
+type fakeSubmitter struct {
+	mu    sync.Mutex
+	roots []string
+	err   error
+}
+
+func (s *fakeSubmitter) SubmitProof(ctx context.Context, hash string, opts ...anchor.SubmitProofOption) (*anchor.AnchorProof, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.roots = append(s.roots, hash)
+	return &anchor.AnchorProof{
+		Id:      hash + ":1",
+		BatchId: "1",
+		Format:  anchor.Proof_CHP_PATH,
+		Hash:    hash,
+		Data: map[string]interface{}{
+			"hash": hash,
+			"branches": []interface{}{
+				map[string]interface{}{
+					"label": "pdb_eth_anchor_branch",
+					"ops": []interface{}{
+						map[string]interface{}{
+							"anchors": []interface{}{
+								map[string]interface{}{"type": "cal", "anchor_id": "1"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (s *fakeSubmitter) submitted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.roots...)
+}
+
+// blockingSubmitter blocks every submission until its context is done.
+type blockingSubmitter struct{}
+
+func (blockingSubmitter) SubmitProof(ctx context.Context, hash string, opts ...anchor.SubmitProofOption) (*anchor.AnchorProof, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newBatcher(t *testing.T, submitter Submitter, opts ...BatcherOption) *Batcher {
+	batcher, err := NewBatcher(submitter, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return batcher
+}
+
+// Adds the hashes concurrently, returning the proofs in order.
+func addAll(t *testing.T, batcher *Batcher, hashes []string) []*anchor.AnchorProof {
+	proofs := make([]*anchor.AnchorProof, len(hashes))
+	errs := make([]error, len(hashes))
+	var wg sync.WaitGroup
+	for i, h := range hashes {
+		wg.Add(1)
+		go func(i int, h string) {
+			defer wg.Done()
+			proofs[i], errs[i] = batcher.Add(context.Background(), h)
+		}(i, h)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return proofs
+}
+
+func TestBatcher_MaxSize(t *testing.T) {
+	submitter := &fakeSubmitter{}
+	batcher := newBatcher(t, submitter, BatcherWithMaxSize(16), BatcherWithMaxDelay(time.Hour))
+	defer batcher.Close(context.Background())
+	hashes := []string{a, b, c, d, e, f, g, h, i, j, k, l, m, n, o, p}
+	proofs := addAll(t, batcher, hashes)
+	// The hashes are added concurrently, so the order of the leaves is unknown.
+	roots := submitter.submitted()
+	if len(roots) != 1 {
+		t.Fatalf("expected the batch root to be submitted once, got %v", roots)
+	}
+	for x, proof := range proofs {
+		res, err := anchor.VerifyOffline(proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Hash != hashes[x] || res.Root != roots[0] {
+			t.Fatalf("proof of '%s' does not lead to the batch root", hashes[x])
+		}
+		if proof.BatchId != "1" {
+			t.Fatal("expected the proof to keep the batch ID")
+		}
+	}
+}
+
+func TestBatcher_MaxDelay(t *testing.T) {
+	submitter := &fakeSubmitter{}
+	batcher := newBatcher(t, submitter, BatcherWithMaxDelay(10*time.Millisecond))
+	defer batcher.Close(context.Background())
+	proof, err := batcher.Add(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := anchor.VerifyOffline(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Root != a {
+		t.Fatal("expected a single hash to be its own root")
+	}
+}
+
+func TestBatcher_Batches(t *testing.T) {
+	submitter := &fakeSubmitter{}
+	batcher := newBatcher(t, submitter, BatcherWithMaxSize(2), BatcherWithMaxDelay(10*time.Millisecond))
+	defer batcher.Close(context.Background())
+	hashes := make([]string, 5)
+	for x := range hashes {
+		hashes[x] = fmt.Sprintf("%064x", x)
+	}
+	for _, proof := range addAll(t, batcher, hashes) {
+		if _, err := anchor.VerifyOffline(proof); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(submitter.submitted()); n != 3 {
+		t.Fatalf("expected 3 batches, got %d", n)
+	}
+}
+
+func TestBatcher_Duplicate(t *testing.T) {
+	submitter := &fakeSubmitter{}
+	batcher := newBatcher(t, submitter, BatcherWithMaxDelay(10*time.Millisecond))
+	defer batcher.Close(context.Background())
+	proofs := addAll(t, batcher, []string{a, b, a})
+	if len(submitter.submitted()) != 1 {
+		t.Fatal("expected a single batch")
+	}
+	if proofs[0].Hash != a || proofs[2].Hash != a || proofs[1].Hash != b {
+		t.Fatal("expected each caller to get the proof of their hash")
+	}
+}
+
+func TestBatcher_SubmitError(t *testing.T) {
+	submitter := &fakeSubmitter{err: anchor.ErrUnavailable}
+	batcher := newBatcher(t, submitter, BatcherWithMaxDelay(time.Millisecond))
+	defer batcher.Close(context.Background())
+	if _, err := batcher.Add(context.Background(), a); !errors.Is(err, anchor.ErrUnavailable) {
+		t.Fatalf("expected the submit error, got %v", err)
+	}
+}
+
+func TestBatcher_Close(t *testing.T) {
+	submitter := &fakeSubmitter{}
+	batcher := newBatcher(t, submitter, BatcherWithMaxDelay(time.Hour))
+	done := make(chan error, 1)
+	go func() {
+		_, err := batcher.Add(context.Background(), a)
+		done <- err
+	}()
+	// Wait for the hash to be pending before closing.
+	for {
+		batcher.mu.Lock()
+		pending := batcher.pending != nil
+		batcher.mu.Unlock()
+		if pending {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := batcher.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batcher.Add(context.Background(), a); err != ErrBatcherClosed {
+		t.Fatalf("expected the batcher to be closed, got %v", err)
+	}
+}
+
+func TestBatcher_InvalidHash(t *testing.T) {
+	batcher := newBatcher(t, &fakeSubmitter{})
+	defer batcher.Close(context.Background())
+	if _, err := batcher.Add(context.Background(), "not hex"); err == nil {
+		t.Fatal("expected an invalid hash error")
+	}
+}
+
+func TestBatcher_InvalidHashSize(t *testing.T) {
+	batcher := newBatcher(t, &fakeSubmitter{})
+	defer batcher.Close(context.Background())
+	if _, err := batcher.Add(context.Background(), "abcd"); err == nil {
+		t.Fatal("expected a hash size error")
+	}
+}
+
+func TestNewBatcher_Invalid(t *testing.T) {
+	if _, err := NewBatcher(&fakeSubmitter{}, BatcherWithMaxSize(0)); err == nil {
+		t.Fatal("expected a max size error")
+	}
+	if _, err := NewBatcher(&fakeSubmitter{}, BatcherWithAlgorithm("md5")); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("expected an unsupported algorithm error, got %v", err)
+	}
+}
+
+func TestBatcher_SubmitTimeout(t *testing.T) {
+	batcher := newBatcher(t, blockingSubmitter{}, BatcherWithMaxDelay(time.Millisecond), BatcherWithSubmitTimeout(10*time.Millisecond))
+	defer batcher.Close(context.Background())
+	if _, err := batcher.Add(context.Background(), a); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the submission to time out, got %v", err)
+	}
+}
+
+func TestBatcher_CloseTimeout(t *testing.T) {
+	batcher := newBatcher(t, blockingSubmitter{}, BatcherWithMaxDelay(time.Hour))
+	done := make(chan error, 1)
+	go func() {
+		_, err := batcher.Add(context.Background(), a)
+		done <- err
+	}()
+	for {
+		batcher.mu.Lock()
+		pending := batcher.pending != nil
+		batcher.mu.Unlock()
+		if pending {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := batcher.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected close to time out, got %v", err)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the submission to be cancelled, got %v", err)
+	}
+}
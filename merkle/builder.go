@@ -93,7 +93,8 @@ func (b *Builder) Writer(key string) *Writer {
 
 // Build constructs the tree and returns the tree struct.
 func (b *Builder) Build() *Tree {
-	levels := build(b.leaves, b.algorithm)
+	// Copy the leaves so appending to the tree and adding to the builder don't share an array.
+	levels := build(append([]string{}, b.leaves...), b.algorithm)
 	return NewTree(b.algorithm, nil, levels)
 }
 
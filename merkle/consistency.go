@@ -0,0 +1,198 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// ConsistencyProof proves the tree of NewSize leaves extends the tree of its first OldSize leaves,
+// i.e. the leaves of the old tree were not modified when appending the new leaves.
+//
+// Promoting the odd node of each level, as the builder does, produces the same tree shape as RFC
+// 6962, so the proof follows RFC 6962 (section 2.1.2), with nodes hashed by the tree's algorithm
+// without prefixes. The roots of trees built from the first OldSize leaves remain comparable
+// to OldRoot.
+type ConsistencyProof struct {
+	Algorithm Hash     `json:"algorithm"` // the algorithm of the trees
+	OldSize   int      `json:"oldSize"`   // the number of leaves in the old tree
+	NewSize   int      `json:"newSize"`   // the number of leaves in the new tree
+	OldRoot   string   `json:"oldRoot"`   // the hex encoded root of the old tree
+	NewRoot   string   `json:"newRoot"`   // the hex encoded root of the new tree
+	Path      []string `json:"path"`      // the hex encoded hashes proving the consistency
+}
+
+// ProveConsistency returns the proof that this tree extends the tree of its first oldSize leaves.
+func (t *Tree) ProveConsistency(oldSize int) (*ConsistencyProof, error) {
+	n := t.NLeaves()
+	if oldSize < 1 || oldSize > n {
+		return nil, fmt.Errorf("old size %d must be between 1 and the tree size %d", oldSize, n)
+	}
+	oldRoot, err := t.subtreeHash(0, oldSize)
+	if err != nil {
+		return nil, err
+	}
+	path, err := t.consistencyPath(oldSize, 0, n, true)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsistencyProof{
+		Algorithm: t.Algorithm,
+		OldSize:   oldSize,
+		NewSize:   n,
+		OldRoot:   hex.EncodeToString(oldRoot),
+		NewRoot:   t.GetRoot(),
+		Path:      path,
+	}, nil
+}
+
+// Validate checks the path proves the old root is consistent with the new root.
+func (p *ConsistencyProof) Validate() (bool, error) {
+	return ValidateConsistencyPath(p.Path, p.OldSize, p.NewSize, p.Algorithm, p.OldRoot, p.NewRoot)
+}
+
+// Returns the consistency path of the first m leaves of the subtree of the leaves [start, end),
+// following SUBPROOF of RFC 6962. The subtree's hash is only included if it isn't the old root.
+func (t *Tree) consistencyPath(m int, start int, end int, complete bool) ([]string, error) {
+	if m == end-start {
+		if complete {
+			return make([]string, 0), nil
+		}
+		h, err := t.subtreeHash(start, end)
+		if err != nil {
+			return nil, err
+		}
+		return []string{hex.EncodeToString(h)}, nil
+	}
+	k := splitSize(end - start)
+	var path []string
+	var sibling []byte
+	var err error
+	if m <= k {
+		if path, err = t.consistencyPath(m, start, start+k, complete); err != nil {
+			return nil, err
+		}
+		sibling, err = t.subtreeHash(start+k, end)
+	} else {
+		if path, err = t.consistencyPath(m-k, start+k, end, false); err != nil {
+			return nil, err
+		}
+		sibling, err = t.subtreeHash(start, start+k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(path, hex.EncodeToString(sibling)), nil
+}
+
+// Returns the hash of the subtree of the leaves [start, end). Complete subtrees are read from the
+// layers, others are split as in RFC 6962.
+func (t *Tree) subtreeHash(start int, end int) ([]byte, error) {
+	size := end - start
+	if size&(size-1) == 0 && start%size == 0 {
+		layer := 0
+		for s := size; s > 1; s >>= 1 {
+			layer++
+		}
+		node := t.Layers[layer][start/size]
+		if layer == 0 {
+			node = toLeaf(node).Value
+		}
+		return decodeHex(node)
+	}
+	k := splitSize(size)
+	left, err := t.subtreeHash(start, start+k)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.subtreeHash(start+k, end)
+	if err != nil {
+		return nil, err
+	}
+	return hashPair(t.Algorithm, left, right), nil
+}
+
+// ValidateConsistencyPath validates the path proves the tree of oldSize leaves with the old root
+// is extended by the tree of newSize leaves with the new root, following RFC 6962 (section
+// 2.1.4.2). An error is returned if the path is malformed rather than just not matching the roots.
+func ValidateConsistencyPath(path []string, oldSize int, newSize int, algorithm Hash, oldRoot string, newRoot string) (bool, error) {
+	if !algorithm.available() {
+		return false, fmt.Errorf("%w '%s'", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if oldSize < 1 || oldSize > newSize {
+		return false, fmt.Errorf("%w: old size %d must be between 1 and the new size %d", ErrMalformedPath, oldSize, newSize)
+	}
+	first, err := decodeHex(oldRoot)
+	if err != nil {
+		return false, fmt.Errorf("old root %w", err)
+	}
+	second, err := decodeHex(newRoot)
+	if err != nil {
+		return false, fmt.Errorf("new root %w", err)
+	}
+	nodes := make([][]byte, 0, len(path)+1)
+	for i, s := range path {
+		h, err := decodeHex(s)
+		if err != nil {
+			return false, fmt.Errorf("path element %d %w", i, err)
+		}
+		nodes = append(nodes, h)
+	}
+	if oldSize == newSize {
+		if len(nodes) != 0 {
+			return false, fmt.Errorf("%w: expected an empty path for trees of the same size", ErrMalformedPath)
+		}
+		return bytes.Equal(first, second), nil
+	}
+	// A complete old tree is a node of the new tree, so its path starts from the old root.
+	if oldSize&(oldSize-1) == 0 {
+		nodes = append([][]byte{first}, nodes...)
+	}
+	if len(nodes) == 0 {
+		return false, fmt.Errorf("%w: path is empty", ErrMalformedPath)
+	}
+	fn, sn := oldSize-1, newSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := nodes[0], nodes[0]
+	for _, c := range nodes[1:] {
+		if sn == 0 {
+			return false, fmt.Errorf("%w: path is too long", ErrMalformedPath)
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = hashPair(algorithm, c, fr)
+			sr = hashPair(algorithm, c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = hashPair(algorithm, sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return false, fmt.Errorf("%w: path is too short", ErrMalformedPath)
+	}
+	return bytes.Equal(fr, first) && bytes.Equal(sr, second), nil
+}
+
+// Returns the largest power of 2 smaller than n, where the tree of n leaves is split.
+func splitSize(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// Returns the hash of the left node followed by the right node.
+func hashPair(algorithm Hash, left []byte, right []byte) []byte {
+	hasher := algorithm.Hash().New()
+	hasher.Write(left)
+	hasher.Write(right)
+	return hasher.Sum(nil)
+}
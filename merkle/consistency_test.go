@@ -0,0 +1,109 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Builds a tree of n leaves, keyed and valued by their index.
+func buildN(n int) *Tree {
+	builder := NewBuilder(SHA256)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("%d", i)
+		builder.Add(key, []byte(key))
+	}
+	return builder.Build()
+}
+
+func TestTree_ProveConsistency(t *testing.T) {
+	for n := 1; n <= 20; n++ {
+		tree := buildN(n)
+		for m := 1; m <= n; m++ {
+			proof, err := tree.ProveConsistency(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The old root matches the root of a tree built from the old leaves only.
+			if proof.OldRoot != buildN(m).GetRoot() || proof.NewRoot != tree.GetRoot() {
+				t.Fatalf("unexpected roots for %d -> %d", m, n)
+			}
+			ok, err := proof.Validate()
+			if err != nil {
+				t.Fatalf("%d -> %d: %s", m, n, err.Error())
+			}
+			if !ok {
+				t.Fatalf("expected the proof of %d -> %d to be valid", m, n)
+			}
+		}
+	}
+}
+
+func TestTree_ProveConsistency_Fixtures(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	tree := builder.Build()
+	proof, err := tree.ProveConsistency(15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.OldRoot != abcdefghijklmno || proof.NewRoot != batch16root {
+		t.Fatalf("unexpected roots %+v", proof)
+	}
+	proof, err = tree.ProveConsistency(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.OldRoot != abcdef {
+		t.Fatalf("unexpected old root '%s'", proof.OldRoot)
+	}
+	if _, err := tree.ProveConsistency(17); err == nil {
+		t.Fatal("expected old size larger than the tree to fail")
+	}
+	if _, err := tree.ProveConsistency(0); err == nil {
+		t.Fatal("expected empty old tree to fail")
+	}
+}
+
+func TestConsistencyProof_Validate_Invalid(t *testing.T) {
+	tree := buildN(13)
+	proof, err := tree.ProveConsistency(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An old tree whose history was rewritten.
+	builder := NewBuilder(SHA256)
+	for i := 0; i < 6; i++ {
+		builder.Add(fmt.Sprintf("%d", i), []byte("rewritten"))
+	}
+	rewritten := *proof
+	rewritten.OldRoot = builder.Build().GetRoot()
+	if ok, err := rewritten.Validate(); ok || err != nil {
+		t.Fatalf("expected a rewritten old tree to be inconsistent, got %v", err)
+	}
+
+	wrongSize := *proof
+	wrongSize.OldSize = 5
+	if ok, _ := wrongSize.Validate(); ok {
+		t.Fatal("expected the wrong old size to be inconsistent")
+	}
+
+	short := *proof
+	short.Path = proof.Path[:len(proof.Path)-1]
+	if _, err := short.Validate(); !errors.Is(err, ErrMalformedPath) {
+		t.Fatalf("expected a short path, got %v", err)
+	}
+
+	long := *proof
+	long.Path = append(append([]string{}, proof.Path...), proof.Path[0])
+	if _, err := long.Validate(); !errors.Is(err, ErrMalformedPath) {
+		t.Fatalf("expected a long path, got %v", err)
+	}
+
+	notHex := *proof
+	notHex.Path = append([]string{"xyz"}, proof.Path[1:]...)
+	if _, err := notHex.Validate(); !errors.Is(err, ErrNotHex) {
+		t.Fatalf("expected non hex path, got %v", err)
+	}
+}
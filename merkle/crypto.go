@@ -39,3 +39,13 @@ func (h Hash) Hash() crypto.Hash {
 		panic("unknown hash")
 	}
 }
+
+// Returns whether the hash is known and its hash function is linked into the binary.
+func (h Hash) available() bool {
+	switch h {
+	case SHA224, SHA256, SHA384, SHA512, SHA3_224, SHA3_256, SHA3_384, SHA3_512:
+		return h.Hash().Available()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,277 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrMalformedPath is returned when a path element doesn't have exactly one of L or R set.
+	ErrMalformedPath = errors.New("malformed merkle path")
+	// ErrNotHex is returned when a leaf, path node or root is not a hex encoded hash.
+	ErrNotHex = errors.New("not a hex encoded hash")
+	// ErrUnsupportedAlgorithm is returned when the hash algorithm is unknown or unavailable.
+	ErrUnsupportedAlgorithm = errors.New("unsupported hash algorithm")
+	// ErrKeyNotFound is returned when proving a key which isn't a leaf of the tree.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrInvalidEncoding is returned when unmarshalling an invalid binary inclusion proof.
+	ErrInvalidEncoding = errors.New("invalid inclusion proof encoding")
+)
+
+// The version of the binary encoding of inclusion proofs.
+const inclusionProofVersion = 1
+
+// InclusionProof proves a leaf is included in the tree with the given root, without holding the
+// rest of the tree.
+type InclusionProof struct {
+	Algorithm Hash    `json:"algorithm"` // the algorithm of the tree
+	Key       string  `json:"key"`       // the key of the leaf
+	Index     int     `json:"index"`     // the index of the leaf
+	TreeSize  int     `json:"treeSize"`  // the number of leaves in the tree
+	Leaf      string  `json:"leaf"`      // the hex encoded hash of the leaf
+	Path      []*Path `json:"path"`      // the path from the leaf to the root
+	Root      string  `json:"root"`      // the hex encoded root of the tree
+}
+
+// Prove returns the inclusion proof of the leaf matching the key.
+func (t *Tree) Prove(key string) (*InclusionProof, error) {
+	index := t.indexOf(key)
+	if index == -1 {
+		return nil, fmt.Errorf("%w '%s'", ErrKeyNotFound, key)
+	}
+	return &InclusionProof{
+		Algorithm: t.Algorithm,
+		Key:       key,
+		Index:     index,
+		TreeSize:  t.NLeaves(),
+		Leaf:      toLeaf(t.Layers[0][index]).Value,
+		Path:      t.path(index),
+		Root:      t.GetRoot(),
+	}, nil
+}
+
+// Validate checks the path starting at the leaf computes the root, and when the tree size is
+// known, that the path is the one of the leaf's index.
+func (p *InclusionProof) Validate() (bool, error) {
+	if p.TreeSize > 0 {
+		if p.Index < 0 || p.Index >= p.TreeSize {
+			return false, fmt.Errorf("%w: index %d is outside of the tree of size %d", ErrMalformedPath, p.Index, p.TreeSize)
+		}
+		sides := pathSides(p.Index, p.TreeSize)
+		if len(sides) != len(p.Path) {
+			return false, fmt.Errorf("%w: expected %d elements for index %d of %d, got %d", ErrMalformedPath, len(sides), p.Index, p.TreeSize, len(p.Path))
+		}
+		for i, left := range sides {
+			if e := p.Path[i]; e != nil && (e.L != "") != left {
+				return false, fmt.Errorf("%w: element %d is on the wrong side for index %d of %d", ErrMalformedPath, i, p.Index, p.TreeSize)
+			}
+		}
+	}
+	return ValidatePath(p.Path, p.Leaf, p.Algorithm, p.Root)
+}
+
+// Returns whether each element of the path of the leaf at the index is on the left, following
+// the builder's promotion of odd nodes.
+func pathSides(index int, size int) []bool {
+	sides := make([]bool, 0)
+	for ; size > 1; size = (size + 1) / 2 {
+		if index%2 != 0 {
+			sides = append(sides, true)
+		} else if index+1 < size {
+			sides = append(sides, false)
+		}
+		index /= 2
+	}
+	return sides
+}
+
+// MarshalBinary encodes the proof in a compact binary form, with the hashes stored as bytes.
+func (p *InclusionProof) MarshalBinary() ([]byte, error) {
+	if p.Index < 0 || p.TreeSize < 0 {
+		return nil, fmt.Errorf("%w: negative index or tree size", ErrInvalidEncoding)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(inclusionProofVersion)
+	writeBytes(&buf, []byte(p.Algorithm))
+	writeBytes(&buf, []byte(p.Key))
+	writeUvarint(&buf, uint64(p.Index))
+	writeUvarint(&buf, uint64(p.TreeSize))
+	for _, h := range []string{p.Leaf, p.Root} {
+		b, err := decodeHex(h)
+		if err != nil {
+			return nil, err
+		}
+		writeBytes(&buf, b)
+	}
+	writeUvarint(&buf, uint64(len(p.Path)))
+	for i, e := range p.Path {
+		if e == nil || (e.L == "") == (e.R == "") {
+			return nil, fmt.Errorf("%w: element %d must have either 'l' or 'r'", ErrMalformedPath, i)
+		}
+		side, h := byte('r'), e.R
+		if e.L != "" {
+			side, h = 'l', e.L
+		}
+		b, err := decodeHex(h)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(side)
+		writeBytes(&buf, b)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the proof from the binary form produced by MarshalBinary.
+func (p *InclusionProof) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil || version != inclusionProofVersion {
+		return fmt.Errorf("%w: unknown version", ErrInvalidEncoding)
+	}
+	d := &InclusionProof{}
+	algorithm, err := readBytes(r)
+	if err != nil {
+		return err
+	}
+	d.Algorithm = Hash(algorithm)
+	key, err := readBytes(r)
+	if err != nil {
+		return err
+	}
+	d.Key = string(key)
+	index, err := readUvarint(r)
+	if err != nil {
+		return err
+	}
+	size, err := readUvarint(r)
+	if err != nil {
+		return err
+	}
+	d.Index, d.TreeSize = int(index), int(size)
+	leaf, err := readBytes(r)
+	if err != nil {
+		return err
+	}
+	root, err := readBytes(r)
+	if err != nil {
+		return err
+	}
+	d.Leaf, d.Root = hex.EncodeToString(leaf), hex.EncodeToString(root)
+	n, err := readUvarint(r)
+	if err != nil {
+		return err
+	}
+	if n > uint64(r.Len()) {
+		return fmt.Errorf("%w: path is truncated", ErrInvalidEncoding)
+	}
+	d.Path = make([]*Path, n)
+	for i := range d.Path {
+		side, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: path is truncated", ErrInvalidEncoding)
+		}
+		b, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		switch side {
+		case 'l':
+			d.Path[i] = &Path{L: hex.EncodeToString(b)}
+		case 'r':
+			d.Path[i] = &Path{R: hex.EncodeToString(b)}
+		default:
+			return fmt.Errorf("%w: unknown side of path element %d", ErrInvalidEncoding, i)
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: unexpected trailing data", ErrInvalidEncoding)
+	}
+	*p = *d
+	return nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	buf.Write(b[:binary.PutUvarint(b, v)])
+}
+
+// Writes the length prefixed bytes.
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	writeUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+}
+
+func readUvarint(r *bytes.Reader) (uint64, error) {
+	v, err := binary.ReadUvarint(r)
+	if err != nil || v > math.MaxInt32 {
+		return 0, fmt.Errorf("%w: invalid integer", ErrInvalidEncoding)
+	}
+	return v, nil
+}
+
+// Reads length prefixed bytes.
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, fmt.Errorf("%w: value is truncated", ErrInvalidEncoding)
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return b, nil
+}
+
+// ValidatePath validates the given path starting at the leaf computes the expected root. An
+// error is returned if the path is malformed rather than just not matching the root.
+func ValidatePath(path []*Path, leaf string, algorithm Hash, expected string) (bool, error) {
+	if !algorithm.available() {
+		return false, fmt.Errorf("%w '%s'", ErrUnsupportedAlgorithm, algorithm)
+	}
+	current, err := decodeHex(leaf)
+	if err != nil {
+		return false, fmt.Errorf("leaf %w", err)
+	}
+	root, err := decodeHex(expected)
+	if err != nil {
+		return false, fmt.Errorf("root %w", err)
+	}
+	for i, v := range path {
+		if v == nil || (v.L == "") == (v.R == "") {
+			return false, fmt.Errorf("%w: element %d must have either 'l' or 'r'", ErrMalformedPath, i)
+		}
+		hasher := algorithm.Hash().New()
+		if v.L != "" {
+			h, err := decodeHex(v.L)
+			if err != nil {
+				return false, fmt.Errorf("path element %d %w", i, err)
+			}
+			hasher.Write(h)
+			hasher.Write(current)
+		} else {
+			h, err := decodeHex(v.R)
+			if err != nil {
+				return false, fmt.Errorf("path element %d %w", i, err)
+			}
+			hasher.Write(current)
+			hasher.Write(h)
+		}
+		current = hasher.Sum(nil)
+	}
+	return bytes.Equal(current, root), nil
+}
+
+// Decodes the hex encoded hash.
+func decodeHex(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("'%s': %w", s, ErrNotHex)
+	}
+	return b, nil
+}
@@ -0,0 +1,165 @@
+package merkle
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInclusionProof_Validate(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	tree := builder.Build()
+	for _, leaf := range tree.GetLeaves() {
+		proof := &InclusionProof{
+			Algorithm: SHA256,
+			Leaf:      leaf.Value,
+			Path:      tree.GetPath(leaf.Key),
+			Root:      tree.GetRoot(),
+		}
+		ok, err := proof.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("expected the proof of '%s' to be valid", leaf.Key)
+		}
+	}
+}
+
+func TestValidatePath_OddLeaves(t *testing.T) {
+	// 'e' is promoted to the root's level without a sibling.
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16[:5])
+	tree := builder.Build()
+	for _, leaf := range tree.GetLeaves() {
+		ok, err := ValidatePath(tree.GetPath(leaf.Key), leaf.Value, SHA256, tree.GetRoot())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("expected the path of '%s' to be valid", leaf.Key)
+		}
+	}
+}
+
+func TestValidatePath_Errors(t *testing.T) {
+	tests := []struct {
+		path      []*Path
+		leaf      string
+		algorithm Hash
+		root      string
+		err       error
+	}{
+		{[]*Path{{}}, a, SHA256, batch16root, ErrMalformedPath},
+		{[]*Path{{L: a, R: b}}, a, SHA256, batch16root, ErrMalformedPath},
+		{[]*Path{nil}, a, SHA256, batch16root, ErrMalformedPath},
+		{[]*Path{{R: "xyz"}}, a, SHA256, batch16root, ErrNotHex},
+		{batch16pathA, "a", SHA256, batch16root, ErrNotHex},
+		{batch16pathA, a, SHA256, "", ErrNotHex},
+		{batch16pathA, a, Hash("md5"), batch16root, ErrUnsupportedAlgorithm},
+	}
+	for i, test := range tests {
+		ok, err := ValidatePath(test.path, test.leaf, test.algorithm, test.root)
+		if ok || !errors.Is(err, test.err) {
+			t.Fatalf("test %d: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestTree_Prove(t *testing.T) {
+	for _, size := range []int{1, 2, 5, 7, 16} {
+		builder := NewBuilder(SHA256)
+		builder.AddBatch(batch16[:size])
+		tree := builder.Build()
+		for i, leaf := range tree.GetLeaves() {
+			proof, err := tree.Prove(leaf.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.Key != leaf.Key || proof.Leaf != leaf.Value || proof.Index != i || proof.TreeSize != size || proof.Root != tree.GetRoot() {
+				t.Fatalf("unexpected proof %+v", proof)
+			}
+			ok, err := proof.Validate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatalf("expected the proof of '%s' in a tree of %d to be valid", leaf.Key, size)
+			}
+		}
+	}
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	if _, err := builder.Build().Prove("z"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected key not found, got %v", err)
+	}
+}
+
+func TestInclusionProof_Validate_WrongIndex(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	proof, err := builder.Build().Prove("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The path of 'a' is only valid for index 0.
+	for _, index := range []int{1, 16, -1} {
+		proof.Index = index
+		if ok, err := proof.Validate(); ok || !errors.Is(err, ErrMalformedPath) {
+			t.Fatalf("expected index %d to be rejected, got %v", index, err)
+		}
+	}
+	proof.Index = 0
+	proof.TreeSize = 5
+	if ok, err := proof.Validate(); ok || !errors.Is(err, ErrMalformedPath) {
+		t.Fatalf("expected tree size to be rejected, got %v", err)
+	}
+}
+
+func TestInclusionProof_Marshal(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16[:7])
+	tree := builder.Build()
+	exp, err := tree.Prove("g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := &InclusionProof{}
+	if err := json.Unmarshal(b, act); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected %+v, got %+v", exp, act)
+	}
+
+	b, err = exp.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	act = &InclusionProof{}
+	if err := act.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected %+v, got %+v", exp, act)
+	}
+	if ok, err := act.Validate(); err != nil || !ok {
+		t.Fatalf("expected the unmarshalled proof to be valid, got %v", err)
+	}
+
+	for n := 0; n < len(b); n++ {
+		if err := act.UnmarshalBinary(b[:n]); !errors.Is(err, ErrInvalidEncoding) {
+			t.Fatalf("expected truncation at %d to be rejected, got %v", n, err)
+		}
+	}
+	if err := act.UnmarshalBinary(append(b, 0)); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("expected trailing data to be rejected, got %v", err)
+	}
+}
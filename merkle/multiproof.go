@@ -0,0 +1,159 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MultiProof proves several leaves are included in the tree with the given root. Siblings shared
+// by the paths of the leaves, or computed from the leaves themselves, are only included once.
+type MultiProof struct {
+	Algorithm Hash     `json:"algorithm"` // the algorithm of the tree
+	TreeSize  int      `json:"treeSize"`  // the number of leaves in the tree
+	Indices   []int    `json:"indices"`   // the indices of the leaves, ascending
+	Keys      []string `json:"keys"`      // the keys of the leaves, in the order of the indices
+	Leaves    []string `json:"leaves"`    // the hex encoded hashes of the leaves, in the order of the indices
+	Siblings  []string `json:"siblings"`  // the hex encoded hashes needed to compute the root, level by level
+	Root      string   `json:"root"`      // the hex encoded root of the tree
+}
+
+// ProveMulti returns the multiproof of the leaves matching the keys.
+func (t *Tree) ProveMulti(keys ...string) (*MultiProof, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no keys to prove")
+	}
+	indices := make([]int, 0, len(keys))
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		index := t.indexOf(k)
+		if index == -1 {
+			return nil, fmt.Errorf("%w '%s'", ErrKeyNotFound, k)
+		}
+		if !seen[index] {
+			seen[index] = true
+			indices = append(indices, index)
+		}
+	}
+	sort.Ints(indices)
+	proof := &MultiProof{
+		Algorithm: t.Algorithm,
+		TreeSize:  t.NLeaves(),
+		Indices:   indices,
+		Keys:      make([]string, 0, len(indices)),
+		Leaves:    make([]string, 0, len(indices)),
+		Siblings:  make([]string, 0),
+		Root:      t.GetRoot(),
+	}
+	for _, i := range indices {
+		leaf := toLeaf(t.Layers[0][i])
+		proof.Keys = append(proof.Keys, leaf.Key)
+		proof.Leaves = append(proof.Leaves, leaf.Value)
+	}
+
+	// Walk up the levels, adding the siblings which can't be computed from the known nodes.
+	known := indices
+	for l := 0; l < len(t.Layers)-1; l++ {
+		level := t.Layers[l]
+		isKnown := make(map[int]bool, len(known))
+		for _, i := range known {
+			isKnown[i] = true
+		}
+		next := make([]int, 0, len(known))
+		for _, i := range known {
+			if sibling := i ^ 1; sibling < len(level) && !isKnown[sibling] {
+				s := level[sibling]
+				if l == 0 {
+					s = toLeaf(s).Value
+				}
+				proof.Siblings = append(proof.Siblings, s)
+			}
+			if len(next) == 0 || next[len(next)-1] != i/2 {
+				next = append(next, i/2)
+			}
+		}
+		known = next
+	}
+	return proof, nil
+}
+
+// Validate checks the root computed from the leaves and the siblings matches the root. An error
+// is returned if the proof is malformed rather than just not matching the root.
+func (p *MultiProof) Validate() (bool, error) {
+	if !p.Algorithm.available() {
+		return false, fmt.Errorf("%w '%s'", ErrUnsupportedAlgorithm, p.Algorithm)
+	}
+	if len(p.Indices) == 0 || len(p.Indices) != len(p.Leaves) {
+		return false, fmt.Errorf("%w: expected a leaf for each index", ErrMalformedPath)
+	}
+	root, err := decodeHex(p.Root)
+	if err != nil {
+		return false, fmt.Errorf("root %w", err)
+	}
+	known := make([]int, len(p.Indices))
+	hashes := make([][]byte, len(p.Indices))
+	for n, i := range p.Indices {
+		if i < 0 || i >= p.TreeSize || (n > 0 && i <= p.Indices[n-1]) {
+			return false, fmt.Errorf("%w: indices must be ascending and within the tree of size %d", ErrMalformedPath, p.TreeSize)
+		}
+		h, err := decodeHex(p.Leaves[n])
+		if err != nil {
+			return false, fmt.Errorf("leaf %d %w", n, err)
+		}
+		known[n], hashes[n] = i, h
+	}
+
+	siblings := p.Siblings
+	sibling := func() ([]byte, error) {
+		if len(siblings) == 0 {
+			return nil, fmt.Errorf("%w: missing siblings", ErrMalformedPath)
+		}
+		h, err := decodeHex(siblings[0])
+		if err != nil {
+			return nil, fmt.Errorf("sibling %d %w", len(p.Siblings)-len(siblings), err)
+		}
+		siblings = siblings[1:]
+		return h, nil
+	}
+	for size := p.TreeSize; size > 1; size = (size + 1) / 2 {
+		nextKnown := make([]int, 0, len(known))
+		nextHashes := make([][]byte, 0, len(known))
+		for n := 0; n < len(known); n++ {
+			i, h := known[n], hashes[n]
+			var left, right []byte
+			switch {
+			case i%2 != 0:
+				s, err := sibling()
+				if err != nil {
+					return false, err
+				}
+				left, right = s, h
+			case i+1 == size:
+				// The odd node is promoted to the next level.
+			case n+1 < len(known) && known[n+1] == i+1:
+				left, right = h, hashes[n+1]
+				n++
+			default:
+				s, err := sibling()
+				if err != nil {
+					return false, err
+				}
+				left, right = h, s
+			}
+			if left != nil {
+				hasher := p.Algorithm.Hash().New()
+				hasher.Write(left)
+				hasher.Write(right)
+				h = hasher.Sum(nil)
+			}
+			nextKnown = append(nextKnown, i/2)
+			nextHashes = append(nextHashes, h)
+		}
+		known, hashes = nextKnown, nextHashes
+	}
+	if len(siblings) != 0 {
+		return false, fmt.Errorf("%w: %d unused siblings", ErrMalformedPath, len(siblings))
+	}
+	return bytes.Equal(hashes[0], root), nil
+}
@@ -0,0 +1,126 @@
+package merkle
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTree_ProveMulti(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	tree := builder.Build()
+	tests := []struct {
+		keys     []string
+		siblings []string
+	}{
+		{[]string{"a"}, []string{b, cd, efgh, ijklmnop}},
+		{[]string{"a", "b"}, []string{cd, efgh, ijklmnop}},
+		{[]string{"p", "a"}, []string{b, o, cd, mn, efgh, ijkl}},
+		{[]string{"a", "c", "e", "g"}, []string{b, d, f, h, ijklmnop}},
+		{[]string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p"}, []string{}},
+	}
+	for _, test := range tests {
+		proof, err := tree.ProveMulti(test.keys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(test.siblings, proof.Siblings) {
+			t.Fatalf("unexpected siblings for %v: %v", test.keys, proof.Siblings)
+		}
+		if proof.Root != batch16root {
+			t.Fatalf("unexpected root '%s'", proof.Root)
+		}
+		ok, err := proof.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("expected the proof of %v to be valid", test.keys)
+		}
+	}
+	if _, err := tree.ProveMulti("a", "z"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected key not found, got %v", err)
+	}
+}
+
+func TestTree_ProveMulti_OddLeaves(t *testing.T) {
+	// 'o' is promoted at the leaves, then hashed with 'mn'.
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16[:15])
+	tree := builder.Build()
+	proof, err := tree.ProveMulti("o", "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{m, ijkl, abcdefgh}, proof.Siblings) || proof.Root != abcdefghijklmno {
+		t.Fatalf("unexpected proof %+v", proof)
+	}
+	if ok, err := proof.Validate(); err != nil || !ok {
+		t.Fatalf("expected the proof to be valid, got %v", err)
+	}
+
+	// Every subset of a small odd tree.
+	builder = NewBuilder(SHA256)
+	builder.AddBatch(batch16[:7])
+	tree = builder.Build()
+	leaves := tree.GetLeaves()
+	for set := 1; set < 1<<len(leaves); set++ {
+		keys := make([]string, 0)
+		for i, leaf := range leaves {
+			if set&(1<<i) != 0 {
+				keys = append(keys, leaf.Key)
+			}
+		}
+		proof, err := tree.ProveMulti(keys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := proof.Validate(); err != nil || !ok {
+			t.Fatalf("expected the proof of %v to be valid, got %v", keys, err)
+		}
+	}
+}
+
+func TestMultiProof_Validate_Invalid(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	tree := builder.Build()
+	prove := func() *MultiProof {
+		proof, err := tree.ProveMulti("c", "k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return proof
+	}
+
+	proof := prove()
+	proof.Leaves[0] = a
+	if ok, err := proof.Validate(); ok || err != nil {
+		t.Fatalf("expected a root mismatch, got %v", err)
+	}
+
+	proof = prove()
+	proof.Siblings = proof.Siblings[1:]
+	if _, err := proof.Validate(); !errors.Is(err, ErrMalformedPath) {
+		t.Fatalf("expected missing siblings, got %v", err)
+	}
+
+	proof = prove()
+	proof.Siblings = append(proof.Siblings, a)
+	if _, err := proof.Validate(); !errors.Is(err, ErrMalformedPath) {
+		t.Fatalf("expected unused siblings, got %v", err)
+	}
+
+	proof = prove()
+	proof.Indices[0], proof.Indices[1] = proof.Indices[1], proof.Indices[0]
+	if _, err := proof.Validate(); !errors.Is(err, ErrMalformedPath) {
+		t.Fatalf("expected unordered indices, got %v", err)
+	}
+
+	proof = prove()
+	proof.Siblings[0] = "xyz"
+	if _, err := proof.Validate(); !errors.Is(err, ErrNotHex) {
+		t.Fatalf("expected non hex sibling, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package merkle
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -82,11 +83,65 @@ func (t *Tree) AddProof(proof *anchor.AnchorProof) {
 	t.Proofs = append(t.Proofs, proof)
 }
 
+// Append hashes the value and appends it to the tree as a new leaf. Only the nodes on the path
+// from the new leaf to the root are updated, so the tree is identical to one built by the
+// builder from the same leaves without rebuilding it.
+func (t *Tree) Append(key string, value []byte) {
+	hasher := t.Algorithm.Hash().New()
+	hasher.Write(value)
+	t.append(key + ":" + hex.EncodeToString(hasher.Sum(nil)))
+}
+
+// AppendRaw appends the hex encoded value to the tree as a new leaf without hashing it.
+func (t *Tree) AppendRaw(key string, value string) error {
+	if _, err := decodeHex(value); err != nil {
+		return fmt.Errorf("leaf %w", err)
+	}
+	t.append(key + ":" + value)
+	return nil
+}
+
+// Appends the leaf and updates the right edge of the tree, promoting odd nodes as the builder.
+func (t *Tree) append(leaf string) {
+	if len(t.Layers) == 0 {
+		t.Layers = [][]string{{leaf}}
+		return
+	}
+	t.Layers[0] = append(t.Layers[0], leaf)
+	index := len(t.Layers[0]) - 1
+	for l := 0; len(t.Layers[l]) > 1; l++ {
+		if l+1 == len(t.Layers) {
+			t.Layers = append(t.Layers, make([]string, 0, 1))
+		}
+		level := t.Layers[l]
+		node := func(i int) string {
+			if l == 0 {
+				return toLeaf(level[i]).Value
+			}
+			return level[i]
+		}
+		// The new node is either the last node of the level, which is promoted, or the right
+		// node of the last pair.
+		parent := node(index)
+		if index%2 != 0 {
+			left, _ := hex.DecodeString(node(index - 1))
+			right, _ := hex.DecodeString(parent)
+			parent = hex.EncodeToString(hashPair(t.Algorithm, left, right))
+		}
+		index /= 2
+		if index == len(t.Layers[l+1]) {
+			t.Layers[l+1] = append(t.Layers[l+1], parent)
+		} else {
+			t.Layers[l+1][index] = parent
+		}
+	}
+}
+
 func (t *Tree) AddPathToProof(proof *anchor.AnchorProof, key string, label string) (*anchor.AnchorProof, error) {
 	switch proof.Format {
-	case "CHP_PATH":
+	case anchor.Proof_CHP_PATH:
 		return t.addPathCHP(proof, key, label)
-	case "CHP_PATH_SIGNED":
+	case anchor.Proof_CHP_PATH_SIGNED:
 		return t.addPathCHP(proof, key, label)
 	default:
 		return nil, fmt.Errorf("proof format '%s' not supported", proof.Format)
@@ -100,34 +155,39 @@ func (t *Tree) addPathCHP(proof *anchor.AnchorProof, key string, label string) (
 		return nil, fmt.Errorf("no leaf found for key '%s'", key)
 	}
 
-	path := t.GetPath(key)
-
-	// Build the new branches. The branches here becomes the provided proof's branches as we add new ops.
-	ops := make([]interface{}, 0)
-	branches := map[string]interface{}{
-		"label":    label,
-		"ops":      &ops,
-		"branches": proof.Data["branches"],
+	chp, err := anchor.ToCHPPath(proof.Data)
+	if err != nil {
+		return nil, err
 	}
 
-	// Loop through each path element and create a CHP path.
-	for i := 0; i < len(path); i++ {
-		lr := make(map[string]string)
-		if path[i].L != "" {
-			lr["l"] = path[i].L
+	// The new branch holds the path from the leaf to the root, followed by the proof's branches
+	// which start from the root.
+	branch := &anchor.CHPBranch{
+		Label:    label,
+		Ops:      make([]*anchor.CHPOp, 0),
+		Branches: chp.Branches,
+	}
+	for _, p := range t.GetPath(key) {
+		if p.L != "" {
+			branch.Ops = append(branch.Ops, &anchor.CHPOp{L: p.L})
 		} else {
-			lr["r"] = path[i].R
+			branch.Ops = append(branch.Ops, &anchor.CHPOp{R: p.R})
 		}
-		ops = append(ops, lr)
-		ops = append(ops, map[string]string{"op": string(t.Algorithm)})
+		branch.Ops = append(branch.Ops, &anchor.CHPOp{Op: string(t.Algorithm)})
 	}
+	chp.Hash = leaf.Value
+	chp.Branches = []*anchor.CHPBranch{branch}
 
-	proof.Data["hash"] = leaf.Value
-	proof.Data["branches"] = []map[string]interface{}{branches}
+	data, err := chp.Map()
+	if err != nil {
+		return nil, err
+	}
+	proof.Data = data
 
 	return &anchor.AnchorProof{
 		Id:         proof.Id,
 		AnchorType: proof.AnchorType,
+		BatchId:    proof.BatchId,
 		Status:     proof.Status,
 		Hash:       leaf.Value,
 		Format:     proof.Format,
@@ -212,9 +272,18 @@ func (t *Tree) GetLevel(level int) []string {
 // GetPath returns the path from a specific leaf all the way to the root hash.
 // leaf must be the matching leaf value (hashed).
 func (t *Tree) GetPath(key string) []*Path {
-	path := make([]*Path, 0)
+	index := t.indexOf(key)
+	// If index is -1, leaf not found. Return an empty path array.
+	if index == -1 {
+		return make([]*Path, 0)
+	}
+	return t.path(index)
+}
+
+// Returns the index of the leaf matching the key, or -1 if not found. The last matching leaf is
+// returned if the key was added more than once.
+func (t *Tree) indexOf(key string) int {
 	index := -1
-	// Find the leaf first
 	leaves := (t.Layers)[0]
 	for i := 0; i < len(leaves); i++ {
 		leaf := toLeaf(leaves[i])
@@ -222,12 +291,12 @@ func (t *Tree) GetPath(key string) []*Path {
 			index = i
 		}
 	}
+	return index
+}
 
-	// If index is still -1, leaf not found. Return an empty path array.
-	if index == -1 {
-		return path
-	}
-
+// Returns the path from the leaf at the index to the root.
+func (t *Tree) path(index int) []*Path {
+	path := make([]*Path, 0)
 	// Loop through each layer and get the index pair. Skip the root layer.
 	for i := 0; i < len(t.Layers)-1; i++ {
 		level := (t.Layers)[i]
@@ -273,33 +342,3 @@ func (t *Tree) Verify(expected string) bool {
 	levels := build(leaves, t.Algorithm)
 	return (levels[len(levels)-1])[0] == expected
 }
-
-// ValidatePath will validate the given path starting at the leaf matches the expected end result.
-// func ValidatePath(path []*Path, leaf string, algorithm Hash, expected string) (bool, error) {
-// 	current, err := hex.DecodeString(leaf)
-// 	if err != nil {
-// 		return false, err
-// 	}
-// 	for _, v := range path {
-// 		hasher := algorithm.Hash().New()
-// 		if v.L != "" {
-// 			h, err := hex.DecodeString(v.L)
-// 			if err != nil {
-// 				return false, err
-// 			}
-// 			hasher.Write(h)
-// 			hasher.Write(current)
-// 		} else if v.R != "" {
-// 			h, err := hex.DecodeString(v.R)
-// 			if err != nil {
-// 				return false, err
-// 			}
-// 			hasher.Write(current)
-// 			hasher.Write(h)
-// 		} else {
-// 			return false, errors.New("either 'L' or 'R' must be provided in the path")
-// 		}
-// 		current = hasher.Sum(nil)
-// 	}
-// 	return hex.EncodeToString(current) == expected, nil
-// }
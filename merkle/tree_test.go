@@ -2,9 +2,12 @@ package merkle
 
 import (
 	_ "crypto/sha256"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/SouthbankSoftware/provendb-sdk-go/anchor"
 )
 
 // Variables for trees with 16 leaves.
@@ -154,27 +157,27 @@ func equal(t *testing.T, exp *[]*Path, act *[]*Path, leaf string) {
 
 }
 
-// // Validates the path by ensuring the final calculated hash DOES match the path.
-// func validate(t *testing.T, path *[]*Path, root string, leaf string) {
-// 	ok, err := ValidatePath(*path, leaf, SHA256, root)
-// 	if err != nil {
-// 		t.Fatal(err)
-// 	}
-// 	if !ok {
-// 		t.Fatalf("root mismatch for '%s'", leaf)
-// 	}
-// }
+// Validates the path by ensuring the final calculated hash DOES match the path.
+func validate(t *testing.T, path *[]*Path, root string, leaf string) {
+	ok, err := ValidatePath(*path, leaf, SHA256, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("root mismatch for '%s'", leaf)
+	}
+}
 
-// // Invalidates the path by ensuring the final calculated hash does NOT match.
-// func invalidate(t *testing.T, path []*Path, root string, leaf string) {
-// 	ok, err := ValidatePath(path, leaf, SHA256, root)
-// 	if err != nil {
-// 		t.Fatal(err)
-// 	}
-// 	if ok {
-// 		t.Fatalf("root should have been different for '%s'", a)
-// 	}
-// }
+// Invalidates the path by ensuring the final calculated hash does NOT match.
+func invalidate(t *testing.T, path []*Path, root string, leaf string) {
+	ok, err := ValidatePath(path, leaf, SHA256, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatalf("root should have been different for '%s'", leaf)
+	}
+}
 
 // func TestTree_Export(t *testing.T) {
 // 	builder := NewBuilder(SHA256)
@@ -336,79 +339,156 @@ func TestTree_Path(t *testing.T) {
 	equal(t, &exp, &act, p)
 }
 
-// func TestValidate(t *testing.T) {
-// 	root := abcdefghijklmnop
-// 	// Path of 'a'
-// 	validate(t, &batch16pathA, root, a)
-// 	// Path of 'b'
-// 	validate(t, &batch16pathB, root, b)
-// 	// Path of 'c'
-// 	validate(t, &batch16pathC, root, c)
-// 	// Path of 'd'
-// 	validate(t, &batch16pathD, root, d)
-// 	// Path of 'e'
-// 	validate(t, &batch16pathE, root, e)
-// 	// Path of 'f'
-// 	validate(t, &batch16pathF, root, f)
-// 	// Path of 'g'
-// 	validate(t, &batch16pathG, root, g)
-// 	// Path of 'h'
-// 	validate(t, &batch16pathH, root, h)
-// 	// Path of 'i'
-// 	validate(t, &batch16pathI, root, i)
-// 	// Path of 'j'
-// 	validate(t, &batch16pathJ, root, j)
-// 	// Path of 'k'
-// 	validate(t, &batch16pathK, root, k)
-// 	// Path of 'l'
-// 	validate(t, &batch16pathL, root, l)
-// 	// Path of 'm'
-// 	validate(t, &batch16pathM, root, m)
-// 	// Path of 'n'
-// 	validate(t, &batch16pathN, root, n)
-// 	// Path of 'o'
-// 	validate(t, &batch16pathO, root, o)
-// 	// Path of 'p'
-// 	validate(t, &batch16pathP, root, p)
-// }
+func TestValidate(t *testing.T) {
+	root := abcdefghijklmnop
+	// Path of 'a'
+	validate(t, &batch16pathA, root, a)
+	// Path of 'b'
+	validate(t, &batch16pathB, root, b)
+	// Path of 'c'
+	validate(t, &batch16pathC, root, c)
+	// Path of 'd'
+	validate(t, &batch16pathD, root, d)
+	// Path of 'e'
+	validate(t, &batch16pathE, root, e)
+	// Path of 'f'
+	validate(t, &batch16pathF, root, f)
+	// Path of 'g'
+	validate(t, &batch16pathG, root, g)
+	// Path of 'h'
+	validate(t, &batch16pathH, root, h)
+	// Path of 'i'
+	validate(t, &batch16pathI, root, i)
+	// Path of 'j'
+	validate(t, &batch16pathJ, root, j)
+	// Path of 'k'
+	validate(t, &batch16pathK, root, k)
+	// Path of 'l'
+	validate(t, &batch16pathL, root, l)
+	// Path of 'm'
+	validate(t, &batch16pathM, root, m)
+	// Path of 'n'
+	validate(t, &batch16pathN, root, n)
+	// Path of 'o'
+	validate(t, &batch16pathO, root, o)
+	// Path of 'p'
+	validate(t, &batch16pathP, root, p)
+}
 
-// func TestValidate_InvalidPath(t *testing.T) {
-// 	// Invalid path of 'a'
-// 	path := []*Path{
-// 		{L: b}, // this is invalid, should be R
-// 		{R: cd},
-// 		{R: efgh},
-// 		{R: ijklmnop},
-// 	}
-// 	invalidate(t, path, batch16root, a)
-
-// 	// Invalid path of 'f'
-// 	path = []*Path{
-// 		{L: e},
-// 		{L: gh}, // this is invalid, should be R
-// 		{L: abcd},
-// 		{R: ijklmnop},
-// 	}
-// 	invalidate(t, path, batch16root, f)
-
-// 	// Invalid path of 'k'
-// 	path = []*Path{
-// 		{R: l},
-// 		{L: ij},
-// 		{L: mnop}, // this is invalid, should be R
-// 		{L: abcdefgh},
-// 	}
-// 	invalidate(t, path, batch16root, k)
-
-// 	// Invalid path of 'p'
-// 	path = []*Path{
-// 		{L: o},
-// 		{L: mn},
-// 		{L: ijkl},
-// 		{R: abcdefgh}, // this is invalid, should be L
-// 	}
-// 	invalidate(t, path, batch16root, p)
-// }
+func TestValidate_InvalidPath(t *testing.T) {
+	// Invalid path of 'a'
+	path := []*Path{
+		{L: b}, // this is invalid, should be R
+		{R: cd},
+		{R: efgh},
+		{R: ijklmnop},
+	}
+	invalidate(t, path, batch16root, a)
+
+	// Invalid path of 'f'
+	path = []*Path{
+		{L: e},
+		{L: gh}, // this is invalid, should be R
+		{L: abcd},
+		{R: ijklmnop},
+	}
+	invalidate(t, path, batch16root, f)
+
+	// Invalid path of 'k'
+	path = []*Path{
+		{R: l},
+		{L: ij},
+		{L: mnop}, // this is invalid, should be R
+		{L: abcdefgh},
+	}
+	invalidate(t, path, batch16root, k)
+
+	// Invalid path of 'p'
+	path = []*Path{
+		{L: o},
+		{L: mn},
+		{L: ijkl},
+		{R: abcdefgh}, // this is invalid, should be L
+	}
+	invalidate(t, path, batch16root, p)
+}
+
+func TestTree_AddPathToProof(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	tree := builder.Build()
+
+	proof := &anchor.AnchorProof{
+		Format: anchor.Proof_CHP_PATH,
+		Hash:   tree.GetRoot(),
+		Data: map[string]interface{}{
+			"hash": tree.GetRoot(),
+			"branches": []interface{}{
+				map[string]interface{}{
+					"label": "pdb_eth_anchor_branch",
+					"ops": []interface{}{
+						map[string]interface{}{
+							"anchors": []interface{}{
+								map[string]interface{}{"type": "cal", "anchor_id": "1"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	p, err := tree.AddPathToProof(proof, "k", "pdb_merkle_branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := anchor.VerifyOffline(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hash != k {
+		t.Fatalf("unexpected proof hash '%s'", res.Hash)
+	}
+	if res.Root != batch16root {
+		t.Fatalf("computed root '%s' does not match tree root", res.Root)
+	}
+}
+
+func TestTree_AddPathToProof_Extra(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16)
+	tree := builder.Build()
+
+	// Keys not modelled by the CHP path are kept.
+	anchors := []interface{}{map[string]interface{}{"type": "cal", "anchor_id": "1", "extra": "anchor"}}
+	proof := &anchor.AnchorProof{
+		Format: anchor.Proof_CHP_PATH,
+		Hash:   tree.GetRoot(),
+		Data: map[string]interface{}{
+			"hash":  tree.GetRoot(),
+			"extra": "path",
+			"branches": []interface{}{
+				map[string]interface{}{
+					"label": "pdb_eth_anchor_branch",
+					"ops":   []interface{}{map[string]interface{}{"anchors": anchors}},
+				},
+			},
+		},
+	}
+	p, err := tree.AddPathToProof(proof, "k", "pdb_merkle_branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Data["extra"] != "path" {
+		t.Fatalf("expected the extra key of the path to be kept, got %v", p.Data)
+	}
+	chp, err := p.CHPPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(chp.Branches[0].Branches[0].Ops[0].Anchors[0].Extra["extra"]) != `"anchor"` {
+		t.Fatal("expected the extra key of the anchor to be kept")
+	}
+}
 
 func TestTree_Root(t *testing.T) {
 	builder := NewBuilder(SHA256)
@@ -423,8 +503,9 @@ func TestTree_Leaves(t *testing.T) {
 	builder := NewBuilder(SHA256)
 	builder.AddBatch(batch16)
 	tree := builder.Build()
-	exp := []string{
-		a, b, c, d, e, f, g, h, i, j, k, l, m, n, o, p,
+	exp := []*Leaf{
+		{"a", a}, {"b", b}, {"c", c}, {"d", d}, {"e", e}, {"f", f}, {"g", g}, {"h", h},
+		{"i", i}, {"j", j}, {"k", k}, {"l", l}, {"m", m}, {"n", n}, {"o", o}, {"p", p},
 	}
 	if !reflect.DeepEqual(exp, tree.GetLeaves()) {
 		t.Fail()
@@ -469,8 +550,11 @@ func TestTree_Level(t *testing.T) {
 		t.Fatal("no match for level 3")
 	}
 
-	// level 4 (leaves)
-	level = []string{a, b, c, d, e, f, g, h, i, j, k, l, m, n, o, p}
+	// level 4 (leaves, stored as 'key:hash')
+	level = []string{
+		"a:" + a, "b:" + b, "c:" + c, "d:" + d, "e:" + e, "f:" + f, "g:" + g, "h:" + h,
+		"i:" + i, "j:" + j, "k:" + k, "l:" + l, "m:" + m, "n:" + n, "o:" + o, "p:" + p,
+	}
 	if !reflect.DeepEqual(level, tree.GetLevel(4)) {
 		t.Fatal("no match for level 4")
 	}
@@ -511,3 +595,55 @@ func TestTree_CountNodes(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTree_Append(t *testing.T) {
+	tree := NewBuilder(SHA256).Build()
+	builder := NewBuilder(SHA256)
+	for n := 1; n <= 40; n++ {
+		key := fmt.Sprintf("%d", n)
+		tree.Append(key, []byte(key))
+		builder.Add(key, []byte(key))
+		exp := builder.Build()
+		if !reflect.DeepEqual(exp.Layers, tree.Layers) {
+			t.Fatalf("appended tree of %d leaves does not match the built tree", n)
+		}
+	}
+}
+
+func TestTree_Append_Builder(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddRaw("a", a).AddRaw("b", b).AddRaw("c", c)
+	tree := builder.Build()
+	if err := tree.AppendRaw("d", d); err != nil {
+		t.Fatal(err)
+	}
+	// Adding to the builder after building must not change the tree.
+	builder.AddRaw("e", e)
+	if leaves := tree.GetLeaves(); leaves[3].Key != "d" {
+		t.Fatalf("expected the appended leaf 'd', got '%s'", leaves[3].Key)
+	}
+	if !tree.Verify(tree.GetRoot()) {
+		t.Fatal("expected the appended tree to verify")
+	}
+}
+
+func TestTree_AppendRaw(t *testing.T) {
+	builder := NewBuilder(SHA256)
+	builder.AddBatch(batch16[:15])
+	tree := builder.Build()
+	if tree.GetRoot() != abcdefghijklmno {
+		t.Fatal("unexpected root")
+	}
+	if err := tree.AppendRaw("p", p); err != nil {
+		t.Fatal(err)
+	}
+	if tree.GetRoot() != batch16root {
+		t.Fatalf("expected the root of 16 leaves, got '%s'", tree.GetRoot())
+	}
+	if err := tree.AppendRaw("q", "xyz"); !errors.Is(err, ErrNotHex) {
+		t.Fatalf("expected non hex leaf, got %v", err)
+	}
+	if tree.NLeaves() != 16 {
+		t.Fatal("invalid leaf should not have been appended")
+	}
+}